@@ -0,0 +1,111 @@
+package bus
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// Event is one state change seen by the daemon. Topic is usually the name of
+// the eww variable that was updated.
+type Event struct {
+	Topic string `json:"topic"`
+	Data  any    `json:"data"`
+	Time  int64  `json:"time"`
+}
+
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	topics []string
+	hub    *Hub
+	once   sync.Once
+}
+
+// Close detaches the subscription from the hub and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Wants reports whether topic matches one of the subscription topics.
+func (s *Subscription) Wants(topic string) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	for _, t := range s.topics {
+		if ok, _ := path.Match(t, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type Hub struct {
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	forward func(Event)
+}
+
+var Default = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe returns a subscription receiving events whose topic matches one
+// of topics (shell glob patterns). No topics means every event.
+func (h *Hub) Subscribe(topics []string) *Subscription {
+	ch := make(chan Event, 64)
+	s := &Subscription{C: ch, ch: ch, topics: topics, hub: h}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// SetForwarder makes every published event also go to f. It is used by
+// processes other than the daemon to hand their events over to it.
+func (h *Hub) SetForwarder(f func(Event)) {
+	h.mu.Lock()
+	h.forward = f
+	h.mu.Unlock()
+}
+
+func (h *Hub) Publish(topic string, data any) {
+	h.Send(Event{Topic: topic, Data: data, Time: time.Now().UnixMilli()})
+}
+
+// Send delivers e to every matching subscriber. Subscribers that fall behind
+// lose events instead of blocking the watchers.
+func (h *Hub) Send(e Event) {
+	h.mu.Lock()
+	forward := h.forward
+	for s := range h.subs {
+		if !s.Wants(e.Topic) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+	h.mu.Unlock()
+
+	if forward != nil {
+		forward(e)
+	}
+}
+
+func Publish(topic string, data any) {
+	Default.Publish(topic, data)
+}
+
+func Subscribe(topics []string) *Subscription {
+	return Default.Subscribe(topics)
+}
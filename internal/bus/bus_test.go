@@ -0,0 +1,88 @@
+package bus
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWants(t *testing.T) {
+	tests := []struct {
+		topics []string
+		topic  string
+		want   bool
+	}{
+		{nil, "AUDIO", true},
+		{[]string{"AUDIO"}, "AUDIO", true},
+		{[]string{"AUDIO"}, "AUDIO_SINK", false},
+		{[]string{"NOTIFICATION*"}, "NOTIFICATION_DND", true},
+		{[]string{"BATTERY", "NET*"}, "NETWORK", true},
+		{[]string{"BATTERY", "NET*"}, "AUDIO", false},
+	}
+	for _, tt := range tests {
+		s := &Subscription{topics: tt.topics}
+		if got := s.Wants(tt.topic); got != tt.want {
+			t.Errorf("%q wants %q = %v, want %v", tt.topics, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestPublishFiltersAndForwards(t *testing.T) {
+	h := NewHub()
+	all := h.Subscribe(nil)
+	audio := h.Subscribe([]string{"AUDIO*"})
+	var forwarded []string
+	h.SetForwarder(func(e Event) { forwarded = append(forwarded, e.Topic) })
+
+	h.Publish("AUDIO", 1)
+	h.Publish("BATTERY", 2)
+
+	if e := <-all.C; e.Topic != "AUDIO" || e.Data != 1 || e.Time == 0 {
+		t.Errorf("first event %+v", e)
+	}
+	if e := <-all.C; e.Topic != "BATTERY" {
+		t.Errorf("second event %+v", e)
+	}
+	if e := <-audio.C; e.Topic != "AUDIO" {
+		t.Errorf("audio got %+v", e)
+	}
+	if len(audio.C) != 0 {
+		t.Errorf("audio got BATTERY too")
+	}
+	if !slices.Equal(forwarded, []string{"AUDIO", "BATTERY"}) {
+		t.Errorf("forwarded %q", forwarded)
+	}
+
+	h.SetForwarder(nil)
+	h.Publish("AUDIO", 3)
+	if len(forwarded) != 2 {
+		t.Errorf("forwarded after SetForwarder(nil): %q", forwarded)
+	}
+}
+
+func TestSlowSubscriberDropsEvents(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(nil)
+	for i := range cap(s.ch) + 10 {
+		h.Publish("X", i) // must not block
+	}
+	if len(s.C) != cap(s.ch) {
+		t.Errorf("%d events queued, want %d", len(s.C), cap(s.ch))
+	}
+	if e := <-s.C; e.Data != 0 {
+		t.Errorf("oldest event %v, want 0: the newest are dropped", e.Data)
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(nil)
+	s.Close()
+	s.Close() // twice is fine
+	h.Publish("X", 1)
+	if _, ok := <-s.C; ok {
+		t.Error("closed subscription got an event")
+	}
+	if len(h.subs) != 0 {
+		t.Error("subscription still attached")
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+	"github.com/hoppxi/wigo/internal/watchers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyApp    string
+	historySince  string
+	historyGrep   string
+	historyLimit  int
+	historyJSON   bool
+	historyCSV    bool
+	historyExport string
+)
+
+var notificationHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Search and export the notification history",
+	Example: `  wigo notification history --app firefox --since 2d --grep invoice
+  wigo notification history --since 2024-06-01 --json
+  wigo notification history --export notifications.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		q := watchers.HistoryQuery{App: historyApp, Limit: historyLimit}
+		if historySince != "" {
+			since, err := parseSince(historySince, time.Now())
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			q.Since = since
+		}
+		if historyGrep != "" {
+			re, err := regexp.Compile("(?i)" + historyGrep)
+			if err != nil {
+				fmt.Println("Error: invalid --grep:", err)
+				os.Exit(1)
+			}
+			q.Grep = re
+		}
+
+		client, err := watchers.DialNotifications()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		list, err := client.History(q)
+		client.Close()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		if historyExport != "" {
+			if err := exportHistory(historyExport, list); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Exported %d notifications to %s\n", len(list), historyExport)
+			return
+		}
+
+		switch {
+		case historyJSON:
+			err = writeHistoryJSON(os.Stdout, list)
+		case historyCSV:
+			err = writeHistoryCSV(os.Stdout, list)
+		default:
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTIME\tAPP\tSUMMARY")
+			for _, n := range list {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", n.ID, time.Unix(n.Timestamp, 0).Format("2006-01-02 15:04"), n.AppName, oneLine(n.Summary))
+			}
+			err = w.Flush()
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// parseSince reads --since: a duration back from now such as 90m, 2d or 1w,
+// or a date (2006-01-02) or RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	for _, unit := range []struct {
+		suffix string
+		d      time.Duration
+	}{{"d", 24 * time.Hour}, {"w", 7 * 24 * time.Hour}} {
+		if n, ok := strings.CutSuffix(s, unit.suffix); ok {
+			if v, err := strconv.ParseFloat(n, 64); err == nil && v >= 0 {
+				return now.Add(-time.Duration(v * float64(unit.d))), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, use e.g. 2d, 12h or 2006-01-02", s)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// exportHistory writes list to file, as CSV for a .csv file and JSON
+// otherwise.
+func exportHistory(file string, list []subscribe.Notification) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		err = writeHistoryCSV(f, list)
+	} else {
+		err = writeHistoryJSON(f, list)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeHistoryJSON(w io.Writer, list []subscribe.Notification) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+func writeHistoryCSV(w io.Writer, list []subscribe.Notification) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "time", "app_name", "summary", "body", "app_icon"})
+	for _, n := range list {
+		cw.Write([]string{
+			strconv.FormatUint(uint64(n.ID), 10),
+			time.Unix(n.Timestamp, 0).Format(time.RFC3339),
+			n.AppName,
+			n.Summary,
+			n.Body,
+			n.AppIcon,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func init() {
+	notificationHistoryCmd.Flags().StringVar(&historyApp, "app", "", "Only notifications of this app (shell glob, case-insensitive)")
+	notificationHistoryCmd.Flags().StringVar(&historySince, "since", "", "Only notifications newer than this, e.g. 2d, 12h or 2006-01-02")
+	notificationHistoryCmd.Flags().StringVar(&historyGrep, "grep", "", "Only notifications whose summary or body match this regular expression (case-insensitive)")
+	notificationHistoryCmd.Flags().IntVar(&historyLimit, "limit", 0, "Print at most this many notifications")
+	notificationHistoryCmd.Flags().BoolVar(&historyJSON, "json", false, "Print JSON")
+	notificationHistoryCmd.Flags().BoolVar(&historyCSV, "csv", false, "Print CSV")
+	notificationHistoryCmd.Flags().StringVar(&historyExport, "export", "", "Write the result to a file, CSV for .csv and JSON otherwise")
+	notificationCmd.AddCommand(notificationHistoryCmd)
+}
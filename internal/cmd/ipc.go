@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hoppxi/wigo/internal/manager"
+	"github.com/spf13/cobra"
+)
+
+var ipcCmd = &cobra.Command{
+	Use:   "ipc <method> [params-json]",
+	Short: "Call a daemon IPC method and print the JSON result",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var params json.RawMessage
+		if len(args) == 2 {
+			if !json.Valid([]byte(args[1])) {
+				fmt.Fprintln(os.Stderr, "Error: params must be valid JSON")
+				os.Exit(1)
+			}
+			params = json.RawMessage(args[1])
+		}
+
+		var result json.RawMessage
+		if err := manager.Manage.SendIPCCommand(args[0], params, &result); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(result))
+	},
+}
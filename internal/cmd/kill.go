@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/hoppxi/wigo/internal/manager"
 	"github.com/spf13/cobra"
@@ -13,16 +12,13 @@ var killCmd = &cobra.Command{
 	Short: "Kill daemon and stop watchers.",
 	Run: func(cmd *cobra.Command, args []string) {
 		// Use the helper method defined in the manager
-		response, err := manager.Manage.SendIPCCommand("STOP")
-		if err != nil {
+		var response string
+		if err := manager.Manage.SendIPCCommand("daemon.stop", nil, &response); err != nil {
 			fmt.Printf("Error: %v (Is the daemon running?)\n", err)
 			return
 		}
 
 		fmt.Printf("Server response: %s\n", response)
-
-		if strings.Contains(response, "OK") {
-			fmt.Println("Niv daemon successfully shut down.")
-		}
+		fmt.Println("Niv daemon successfully shut down.")
 	},
 }
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hoppxi/wigo/internal/manager"
+	"github.com/hoppxi/wigo/pkg/search"
+	"github.com/spf13/cobra"
+)
+
+var launchCmd = &cobra.Command{
+	Use:   "launch <token|id> [file|url...]",
+	Short: "launch a search result by its launch token or id",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := manager.Config.Load()
+		if err := search.Launch(args[0], cfg.GetString("apps.terminal"), args[1:]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
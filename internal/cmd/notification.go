@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/manager"
 	"github.com/hoppxi/wigo/internal/watchers"
 	"github.com/spf13/cobra"
 )
@@ -11,14 +15,20 @@ import (
 var (
 	dndFlag           string
 	dndToggle         bool
+	dndForFlag        time.Duration
 	dndState          bool
 	clearFlag         bool
 	countFlag         bool
 	closeID           uint32
 	closeViewOnly     bool
+	closeGroupFlag    string
+	dismissGroupFlag  string
+	dismissAllFlag    bool
 	execFlag          []string
 	actionIDFlag      string
 	actionNotifIDFlag uint32
+	activationToken   string
+	replyFlag         string
 	notifyFlag        bool
 
 	appNameFlag       string
@@ -34,91 +44,148 @@ var notificationCmd = &cobra.Command{
 	Use:   "notification",
 	Short: "Notification daemon and tools",
 	Run: func(cmd *cobra.Command, args []string) {
-		if notifyFlag {
-			if summaryFlag == "" || bodyFlag == "" {
-				fmt.Println("Error: --summary (-s) and --body (-b) flags are required when using --notify.")
-				_ = cmd.Usage()
-				return
-			}
-
-			hints := make(map[string]dbus.Variant)
-
-			id := (*watchers.NotificationDaemon).Notify(
-				&watchers.NotificationDaemon{},
-				appNameFlag,
-				replacesIDFlag,
-				appIconFlag,
-				summaryFlag,
-				bodyFlag,
-				actionsFlag,
-				hints,
-				expireTimeoutFlag,
-			)
-
-			fmt.Printf("Notification sent. ID: %d\n", id)
+		if !notificationClientFlags(cmd) {
+			manager.Config.Apply()
+			watchers.StartNotificationWatcher(execFlag)
 			return
 		}
 
-		if clearFlag {
-			if err := watchers.NotificationHelper.ClearHistory(); err != nil {
-				fmt.Println("Error clearing history:", err)
-			} else {
-				fmt.Println("History cleared")
-			}
-			return
+		client, err := watchers.DialNotifications()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
 		}
+		defer client.Close()
 
-		if dndToggle {
-			newState := watchers.NotificationHelper.ToggleDND()
-			fmt.Println(newState)
-			return
+		if err := runNotificationClient(cmd, client); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
 		}
+	},
+}
 
-		if dndState {
-			state := watchers.NotificationHelper.GetDNDState()
-			fmt.Println(state)
-			return
+// notificationClientFlags reports whether a flag asks for something of the
+// running daemon, rather than to run the daemon itself.
+func notificationClientFlags(cmd *cobra.Command) bool {
+	for _, name := range []string{"notify", "clear-history", "dnd-for", "dnd-toggle", "dnd-state", "dnd",
+		"count", "close", "close-group", "dismiss-group", "dismiss-all", "action", "reply"} {
+		if cmd.Flags().Changed(name) {
+			return true
 		}
+	}
+	return false
+}
 
-		if dndFlag != "" {
-			if err := watchers.NotificationHelper.SetDNDState(dndFlag); err != nil {
-				fmt.Println("Error:", err)
-			} else {
-				fmt.Println("DND set to", dndFlag)
-			}
-			return
+func runNotificationClient(cmd *cobra.Command, client *watchers.NotificationClient) error {
+	switch {
+	case notifyFlag:
+		if summaryFlag == "" || bodyFlag == "" {
+			fmt.Println("Error: --summary (-s) and --body (-b) flags are required when using --notify.")
+			return cmd.Usage()
 		}
 
-		if countFlag {
-			fmt.Println(watchers.NotificationHelper.GetHistoryCount())
-			return
+		id, err := client.Notify(appNameFlag, replacesIDFlag, appIconFlag, summaryFlag, bodyFlag,
+			actionsFlag, nil, expireTimeoutFlag)
+		if err != nil {
+			return err
 		}
+		fmt.Printf("Notification sent. ID: %d\n", id)
 
-		if closeID != 0 {
-			if closeViewOnly {
-				watchers.NotificationHelper.CloseNotificationViewOnly(closeID)
-			} else {
-				watchers.NotificationHelper.CloseNotificationByID(closeID)
-			}
-			return
+	case clearFlag:
+		if err := client.ClearHistory(); err != nil {
+			return fmt.Errorf("clearing history: %w", err)
+		}
+		fmt.Println("History cleared")
+
+	case dndForFlag != 0:
+		if err := client.SetDNDFor(dndForFlag); err != nil {
+			return err
+		}
+		fmt.Println("DND on for", dndForFlag)
+
+	case dndToggle:
+		on, err := client.SetDND("toggle")
+		if err != nil {
+			return err
+		}
+		fmt.Println(on)
+
+	case dndState:
+		on, err := client.DND()
+		if err != nil {
+			return err
 		}
+		fmt.Println(on)
 
-		if actionIDFlag != "" && actionNotifIDFlag != 0 {
-			err := watchers.NotificationHelper.InvokeAction(actionNotifIDFlag, actionIDFlag)
+	case dndFlag != "":
+		if _, err := client.SetDND(dndFlag); err != nil {
+			return err
+		}
+		fmt.Println("DND set to", dndFlag)
+
+	case countFlag:
+		n, err := client.HistoryCount()
+		if err != nil {
+			return err
+		}
+		fmt.Println(n)
+
+	case closeID != 0:
+		return client.Dismiss(closeID, closeViewOnly)
+
+	case closeGroupFlag != "":
+		n, err := client.DismissGroup(closeGroupFlag, true)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Closed %d notifications of %s\n", n, closeGroupFlag)
+
+	case dismissGroupFlag != "":
+		n, err := client.DismissGroup(dismissGroupFlag, false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dismissed %d notifications of %s\n", n, dismissGroupFlag)
+
+	case dismissAllFlag:
+		n, err := client.DismissAll()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dismissed %d notifications\n", n)
+
+	case actionIDFlag != "":
+		if actionNotifIDFlag == 0 {
+			return fmt.Errorf("--action needs --id")
+		}
+		if err := client.InvokeAction(actionNotifIDFlag, actionIDFlag, activationToken); err != nil {
+			return fmt.Errorf("performing action: %w", err)
+		}
+		fmt.Printf("Action '%s' invoked on notification %d\n", actionIDFlag, actionNotifIDFlag)
+
+	case cmd.Flags().Changed("reply"):
+		if actionNotifIDFlag == 0 {
+			return fmt.Errorf("--reply needs --id")
+		}
+		text := replyFlag
+		if text == "-" {
+			data, err := io.ReadAll(os.Stdin)
 			if err != nil {
-				fmt.Println("Error performing action:", err)
-			} else {
-				fmt.Printf("Action '%s' invoked on notification %d\n", actionIDFlag, actionNotifIDFlag)
+				return fmt.Errorf("reading the reply: %w", err)
 			}
-			return
+			text = strings.TrimSuffix(string(data), "\n")
 		}
-
-		watchers.StartNotificationWatcher(execFlag)
-	},
+		if err := client.Reply(actionNotifIDFlag, text); err != nil {
+			return fmt.Errorf("sending reply: %w", err)
+		}
+		fmt.Printf("Replied to notification %d\n", actionNotifIDFlag)
+	}
+	return nil
 }
 
 func init() {
 	notificationCmd.Flags().StringVar(&dndFlag, "dnd", "", "Set do-not-disturb explicitly (on|off)")
+	notificationCmd.Flags().DurationVar(&dndForFlag, "dnd-for", 0, "Turn do-not-disturb on for a duration, e.g. 1h or 30m")
 	notificationCmd.Flags().BoolVar(&dndToggle, "dnd-toggle", false, "Toggle DND state")
 	notificationCmd.Flags().BoolVar(&dndState, "dnd-state", false, "Print current DND state (true/false)")
 	notificationCmd.Flags().BoolVar(&clearFlag, "clear-history", false, "Clear notification history")
@@ -126,8 +193,13 @@ func init() {
 	notificationCmd.Flags().StringSliceVar(&execFlag, "exec", nil, "Command to run for notifications")
 	notificationCmd.Flags().Uint32Var(&closeID, "close", 0, "Close notification by ID")
 	notificationCmd.Flags().BoolVar(&closeViewOnly, "view-only", false, "When used with --close: only close from current NOTIFICATION view, keep history")
+	notificationCmd.Flags().StringVar(&closeGroupFlag, "close-group", "", "Close every notification of a group (see NOTIFICATION_GROUPS), keep history")
+	notificationCmd.Flags().StringVar(&dismissGroupFlag, "dismiss-group", "", "Close every notification of a group and remove it from history")
+	notificationCmd.Flags().BoolVar(&dismissAllFlag, "dismiss-all", false, "Close every showing notification, keep history")
 	notificationCmd.Flags().StringVar(&actionIDFlag, "action", "", "Perform action ID on a notification")
-	notificationCmd.Flags().Uint32Var(&actionNotifIDFlag, "id", 0, "Notification ID for --action")
+	notificationCmd.Flags().Uint32Var(&actionNotifIDFlag, "id", 0, "Notification ID for --action and --reply")
+	notificationCmd.Flags().StringVar(&activationToken, "activation-token", os.Getenv("XDG_ACTIVATION_TOKEN"), "XDG activation token handed to the app with --action")
+	notificationCmd.Flags().StringVar(&replyFlag, "reply", "", "Send an inline reply to notification --id; - reads it from stdin")
 	notificationCmd.Flags().BoolVar(&notifyFlag, "notify", false, "Send a new desktop notification")
 	notificationCmd.Flags().StringVarP(&appNameFlag, "app-name", "a", "CLI Notification", "Application name for the notification")
 	notificationCmd.Flags().Uint32VarP(&replacesIDFlag, "replace-id", "r", 0, "ID of the notification to replace")
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/hoppxi/wigo/internal/manager"
 	"github.com/spf13/cobra"
@@ -11,19 +10,16 @@ import (
 var reloadCmd = &cobra.Command{
 	Use:   "reload",
 	Short: "Reload EWW widgets and watchers",
+	Long: `Reload re-reads wigo.yaml and restarts the watchers and widgets inside the
+running daemon. eww is only restarted when eww.yuck, eww.scss or src changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		response, err := manager.Manage.SendIPCCommand("STOP")
-		if err != nil {
+		var response string
+		if err := manager.Manage.SendIPCCommand("daemon.reload", nil, &response); err != nil {
 			fmt.Printf("Error: %v (Is the daemon running?)\n", err)
 			return
 		}
 
 		fmt.Printf("Server response: %s\n", response)
-
-		startCmd.Run(cmd, args) // restart in-place
-
-		if strings.Contains(response, "OK") {
-			fmt.Println("Niv daemon successfully reloaded.")
-		}
+		fmt.Println("Niv daemon successfully reloaded.")
 	},
 }
@@ -32,17 +32,27 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		if cmd.Name() == "start" {
+		// search scans the disk itself when the daemon is down, launch
+		// does not need it
+		if cmd.Name() == "start" || cmd.Name() == "search" || cmd.Name() == "launch" {
 			return
 		}
 
 		conn, err := manager.Manage.ConnectIPC()
 		if err != nil {
-			fmt.Println("Error:", err)
-			fmt.Println("Hint: run `wigo start` first")
+			problem, hint := manager.DaemonHint()
+			if problem == "" {
+				problem = err.Error()
+			}
+			fmt.Println("Error:", problem)
+			if hint != "" {
+				fmt.Println("Hint:", hint)
+			}
 			os.Exit(1)
 		}
 		conn.Close()
+
+		manager.ForwardEvents()
 	},
 }
 
@@ -60,6 +70,7 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(killCmd)
 	rootCmd.AddCommand(reloadCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(toggleCmd)
 	rootCmd.AddCommand(openCmd)
 	rootCmd.AddCommand(closeCmd)
@@ -70,7 +81,11 @@ func init() {
 	rootCmd.AddCommand(bluetoothCmd)
 	rootCmd.AddCommand(displayCmd)
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(launchCmd)
 	rootCmd.AddCommand(notificationCmd)
 	rootCmd.AddCommand(wallpaperCmd)
 	rootCmd.AddCommand(idleCmd)
+	rootCmd.AddCommand(ipcCmd)
+	rootCmd.AddCommand(subscribeCmd)
+	rootCmd.AddCommand(stateCmd)
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/hoppxi/wigo/internal/manager"
 	"github.com/hoppxi/wigo/pkg/search"
 	"github.com/spf13/cobra"
@@ -9,9 +11,31 @@ import (
 var searchCmd = &cobra.Command{
 	Use:   "search ':filter term'",
 	Short: "search and do other stuff",
-	Args:  cobra.ExactArgs(1),
+	Args:  cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if id, _ := cmd.Flags().GetString("record"); id != "" {
+			if err := search.RecordLaunch(id); err != nil {
+				fmt.Println("Error:", err)
+			}
+			return
+		}
+
 		cfg := manager.Config.Load()
+		search.Apps = searchDaemonApps
 		search.Search(args, cfg, "launcher-ext")
 	},
 }
+
+func init() {
+	searchCmd.Flags().String("record", "", "Record that the result with this id was launched, to rank it higher")
+}
+
+// searchDaemonApps queries the daemon's app index, and scans the disk when
+// the daemon is not running.
+func searchDaemonApps(term string) []search.Result {
+	var results []search.Result
+	if err := manager.Manage.SendIPCCommand("search.query", map[string]string{"term": term}, &results); err != nil {
+		return search.SearchDesktopApps(term)
+	}
+	return results
+}
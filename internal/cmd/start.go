@@ -18,38 +18,60 @@ var startCmd = &cobra.Command{
 		if conn, err := manager.Manage.ConnectIPC(); err == nil {
 			defer conn.Close()
 			fmt.Println("Daemon already running. Sending start command...")
-			if _, err := manager.Manage.SendIPCCommand("START"); err != nil {
+			if err := manager.Manage.SendIPCCommand("daemon.start", nil, nil); err != nil {
 				fmt.Printf("Failed to send start command: %v\n", err)
 			}
 			return
 		}
 
-		fmt.Println("Starting daemon...")
+		if err := manager.Manage.Lock(); err != nil {
+			fmt.Println("Error:", err)
+			if running, ok := err.(*manager.AlreadyRunningError); ok && running.PID > 0 {
+				fmt.Printf("Hint: it is not answering on its socket, run `kill %d` and try again\n", running.PID)
+			}
+			os.Exit(1)
+		}
 
-		ewwCmd, ewwCancel := manager.NewCmd("eww", "daemon", "--no-daemonize")
-		eww := manager.StartTrackedCmd(ewwCmd, ewwCancel)
-		if eww == nil {
-			fmt.Println("Failed to start eww daemon")
+		sinks, _ := cmd.Flags().GetStringSlice("sink")
+		if headless, _ := cmd.Flags().GetBool("headless"); headless {
+			sinks = []string{"stdout"}
+		}
+		if err := manager.Manage.UseSinks(sinks); err != nil {
+			fmt.Println("Error:", err)
 			return
 		}
 
+		fmt.Fprintln(os.Stderr, "Starting daemon...")
+
+		if manager.Manage.UsesEww() {
+			if err := manager.Manage.StartEww(); err != nil {
+				fmt.Println("Failed to start eww daemon:", err)
+				return
+			}
+		}
+
 		go manager.Manage.StartIPCServer()
 
 		time.Sleep(100 * time.Millisecond)
 
-		if _, err := manager.Manage.SendIPCCommand("START"); err != nil {
+		if err := manager.Manage.SendIPCCommand("daemon.start", nil, nil); err != nil {
 			fmt.Printf("Failed to initialize daemon: %v\n", err)
 			manager.Manage.StopAll()
 			return
 		}
 
-		fmt.Println("Daemon started successfully. Press Ctrl+C to stop.")
+		fmt.Fprintln(os.Stderr, "Daemon started successfully. Press Ctrl+C to stop.")
 
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 
-		fmt.Println("\nReceived shutdown signal, stopping all processes and watchers...")
+		fmt.Fprintln(os.Stderr, "\nReceived shutdown signal, stopping all processes and watchers...")
 		manager.Manage.StopAll()
 	},
 }
+
+func init() {
+	startCmd.Flags().StringSlice("sink", []string{"eww"}, "Frontends to publish to: eww, stdout")
+	startCmd.Flags().Bool("headless", false, "Run without eww and print updates as JSON lines (same as --sink stdout)")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hoppxi/wigo/internal/manager"
+	"github.com/spf13/cobra"
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Inspect the last value of the variables the daemon published",
+}
+
+var stateGetCmd = &cobra.Command{
+	Use:   "get <variable>",
+	Short: "Print the last value of one variable, e.g. NETWORK_INFO",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		printState("state.get", map[string]string{"name": args[0]})
+	},
+}
+
+var stateDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Print the last value of every variable",
+	Run: func(cmd *cobra.Command, args []string) {
+		printState("state.dump", nil)
+	},
+}
+
+var stateReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Push every cached value to eww again",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := manager.Manage.SendIPCCommand("state.replay", nil, nil); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func printState(method string, params any) {
+	var result json.RawMessage
+	if err := manager.Manage.SendIPCCommand(method, params, &result); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		fmt.Println(string(result))
+		return
+	}
+	fmt.Println(out.String())
+}
+
+func init() {
+	stateCmd.AddCommand(stateGetCmd, stateDumpCmd, stateReplayCmd)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/manager"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the health of the daemon, its watchers and child processes",
+	Run: func(cmd *cobra.Command, args []string) {
+		var raw json.RawMessage
+		if err := manager.Manage.SendIPCCommand("daemon.status", nil, &raw); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (Is the daemon running?)\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			fmt.Println(string(raw))
+			return
+		}
+
+		var st manager.DaemonStatus
+		if err := json.Unmarshal(raw, &st); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Daemon: %s (pid %d", st.State, st.PID)
+		if !st.StartedAt.IsZero() {
+			fmt.Printf(", up %s", since(st.StartedAt))
+		}
+		fmt.Println(")")
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WATCHER\tSTATE\tRESTARTS\tLAST EVENT\tERROR")
+		for _, wi := range st.Watchers {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", wi.Name, wi.State, wi.Restarts, ago(wi.LastEvent), wi.Error)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "PROCESS\tPID\tSTATE\tRESTARTS\tUPTIME\tERROR")
+		for _, p := range st.Processes {
+			uptime := "-"
+			if p.State == "running" {
+				uptime = since(p.StartedAt)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%s\n", p.Name, p.PID, p.State, p.Restarts, uptime, p.Error)
+		}
+		w.Flush()
+	},
+}
+
+func since(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return since(t) + " ago"
+}
+
+func init() {
+	statusCmd.Flags().Bool("json", false, "Print the raw JSON report")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hoppxi/wigo/internal/ipc"
+	"github.com/spf13/cobra"
+)
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe [topics...]",
+	Short: "Stream daemon state changes as JSON lines",
+	Long: `Stream every variable the daemon publishes (AUDIO_INFO, NETWORK_INFO,
+WORKSPACES, NOTIFICATION_RECEIVED, ...) as one JSON object per line.
+Topics are matched as shell globs, e.g. 'NOTIFICATION*'. No topics means all.
+With --replay the current value of every matching variable is printed first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ipc.Connect()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		replay, _ := cmd.Flags().GetBool("replay")
+		err = client.Stream("events.subscribe", subscribeParams{Topics: args, Replay: replay}, func(event json.RawMessage) error {
+			_, err := fmt.Println(string(event))
+			return err
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+type subscribeParams struct {
+	Topics []string `json:"topics"`
+	Replay bool     `json:"replay,omitempty"`
+}
+
+func init() {
+	subscribeCmd.Flags().Bool("replay", false, "Start with the current value of every matching variable")
+}
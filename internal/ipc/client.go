@@ -0,0 +1,175 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RuntimeDir is where the daemon keeps its socket and lock file.
+func RuntimeDir() (string, error) {
+	var baseDir string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		baseDir = runtimeDir
+	} else {
+		baseDir = os.TempDir()
+	}
+
+	dir := filepath.Join(baseDir, "wigo")
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+func SocketPath() string {
+	dir, err := RuntimeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "wigo-socket.sock")
+	}
+	return filepath.Join(dir, "socket.sock")
+}
+
+func Dial() (net.Conn, error) {
+	return net.DialTimeout("unix", SocketPath(), 500*time.Millisecond)
+}
+
+type SocketState int
+
+const (
+	// SocketMissing means there is no socket file, no daemon was started.
+	SocketMissing SocketState = iota
+	// SocketStale means the file exists but nobody accepts connections,
+	// usually because the daemon crashed.
+	SocketStale
+	// SocketLive means a daemon answered.
+	SocketLive
+)
+
+func (s SocketState) String() string {
+	switch s {
+	case SocketStale:
+		return "stale"
+	case SocketLive:
+		return "running"
+	}
+	return "not running"
+}
+
+// Probe tells a live daemon socket apart from a stale or missing one.
+func Probe() SocketState {
+	path := SocketPath()
+	if _, err := os.Stat(path); err != nil {
+		return SocketMissing
+	}
+
+	conn, err := net.DialTimeout("unix", path, 500*time.Millisecond)
+	if err != nil {
+		return SocketStale
+	}
+	conn.Close()
+	return SocketLive
+}
+
+// Client keeps one connection open so several calls can share it.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	enc    *json.Encoder
+	nextID uint64
+}
+
+func Connect() (*Client, error) {
+	conn, err := Dial()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		enc:    json.NewEncoder(conn),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Call sends method with params and decodes the result into result, which
+// may be nil when the caller does not care about it.
+func (c *Client) Call(method string, params any, result any) error {
+	c.nextID++
+	req := Request{Version: Version, ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encode params: %w", err)
+		}
+		req.Params = data
+	}
+
+	if err := c.enc.Encode(req); err != nil {
+		return err
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("response id %d does not match request id %d", resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// Stream calls a streaming method and invokes onEvent for every event until
+// the connection ends or onEvent returns an error.
+func (c *Client) Stream(method string, params any, onEvent func(event json.RawMessage) error) error {
+	var ack string
+	if err := c.Call(method, params, &ack); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		var resp Response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return fmt.Errorf("decode event: %w", err)
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if len(resp.Event) == 0 {
+			continue
+		}
+		if err := onEvent(resp.Event); err != nil {
+			return err
+		}
+	}
+}
+
+// Call is a one-shot helper that dials, calls and closes.
+func Call(method string, params any, result any) error {
+	c, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Call(method, params, result)
+}
@@ -0,0 +1,71 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the protocol version spoken over the daemon socket. Every
+// request and response carries it so older clients can be told apart.
+const Version = 1
+
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternal       = -32603
+)
+
+// Request is a single newline-delimited JSON message sent to the daemon.
+type Request struct {
+	Version int             `json:"v"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers the Request with the same ID. Exactly one of Result or
+// Error is set, except for messages of a stream which only carry Event.
+type Response struct {
+	Version int             `json:"v"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	Event   json.RawMessage `json:"event,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ipc error %d: %s", e.Code, e.Message)
+}
+
+// Errorf builds an *Error that handlers can return to control the code sent
+// back to the client.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// DecodeParams unmarshals the request params into v. Missing params are not
+// an error, v is simply left untouched.
+func (r *Request) DecodeParams(v any) error {
+	if len(r.Params) == 0 || string(r.Params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(r.Params, v); err != nil {
+		return Errorf(CodeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+// Legacy plain-text verbs understood before the JSON protocol existed.
+var legacyVerbs = map[string]string{
+	"STOP":   "daemon.stop",
+	"STATUS": "daemon.status",
+	"START":  "daemon.start",
+	"RELOAD": "daemon.reload",
+}
@@ -0,0 +1,273 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// legacyReadTimeout bounds how long a legacy verb may take to arrive, since
+// those clients keep the connection open waiting for the reply.
+const legacyReadTimeout = 500 * time.Millisecond
+
+// maxAcceptDelay caps the back off after failed Accepts, as when the daemon
+// runs out of file descriptors.
+const maxAcceptDelay = time.Second
+
+// HandlerFunc serves one method. The returned value is marshalled into the
+// response result; a returned *Error keeps its code, any other error is
+// reported as CodeInternal.
+type HandlerFunc func(req *Request) (any, error)
+
+// StreamFunc serves a long-lived method. It is called after the initial
+// response has been sent and keeps calling send until done is closed, which
+// happens when the client hangs up.
+type StreamFunc func(req *Request, send func(event any) error, done <-chan struct{}) error
+
+type Server struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	streams  map[string]StreamFunc
+}
+
+func NewServer() *Server {
+	return &Server{
+		handlers: make(map[string]HandlerFunc),
+		streams:  make(map[string]StreamFunc),
+	}
+}
+
+// HandleStream registers a streaming method. The client first receives a
+// normal response whose result is "subscribed", followed by one message per
+// event sharing the request ID.
+func (s *Server) HandleStream(method string, f StreamFunc) {
+	s.mu.Lock()
+	s.streams[method] = f
+	s.mu.Unlock()
+}
+
+func (s *Server) stream(method string) (StreamFunc, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.streams[method]
+	return f, ok
+}
+
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	s.handlers[method] = h
+	s.mu.Unlock()
+}
+
+func (s *Server) handler(method string) (HandlerFunc, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.handlers[method]
+	return h, ok
+}
+
+// Serve accepts connections until the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	var delay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else {
+				delay = min(2*delay, maxAcceptDelay)
+			}
+			log.Printf("ipc: accept error: %v; retrying in %v", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	first, err := r.Peek(1)
+	if err != nil {
+		return
+	}
+
+	if first[0] != '{' {
+		s.serveLegacy(conn, r)
+		return
+	}
+
+	enc := json.NewEncoder(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			if req, f, ok := s.streamRequest(line); ok {
+				s.serveStream(conn, r, enc, req, f)
+				return
+			}
+			if encErr := enc.Encode(s.dispatch(line)); encErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("ipc: read error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(line []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &Response{Version: Version, Error: Errorf(CodeParseError, "parse error: %v", err)}
+	}
+
+	resp := &Response{Version: Version, ID: req.ID}
+	if req.Version > Version {
+		resp.Error = Errorf(CodeInvalidRequest, "unsupported protocol version %d", req.Version)
+		return resp
+	}
+
+	result, err := s.call(&req)
+	if err != nil {
+		resp.Error = toError(err)
+		return resp
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = Errorf(CodeInternal, "encode result: %v", err)
+		return resp
+	}
+	resp.Result = data
+	return resp
+}
+
+func (s *Server) streamRequest(line []byte) (*Request, StreamFunc, bool) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return nil, nil, false
+	}
+	f, ok := s.stream(req.Method)
+	if !ok || req.Version > Version {
+		return nil, nil, false
+	}
+	return &req, f, true
+}
+
+// serveStream hands the connection over to a StreamFunc. Nothing else is read
+// from the client except EOF, which ends the stream.
+func (s *Server) serveStream(conn net.Conn, r *bufio.Reader, enc *json.Encoder, req *Request, f StreamFunc) {
+	var mu sync.Mutex
+	write := func(resp *Response) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return enc.Encode(resp)
+	}
+
+	if err := write(&Response{Version: Version, ID: req.ID, Result: json.RawMessage(`"subscribed"`)}); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(io.Discard, r)
+	}()
+
+	send := func(event any) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return write(&Response{Version: Version, ID: req.ID, Event: data})
+	}
+
+	if err := f(req, send, done); err != nil {
+		_ = write(&Response{Version: Version, ID: req.ID, Error: toError(err)})
+	}
+	_ = conn.Close()
+	<-done
+}
+
+func (s *Server) call(req *Request) (any, error) {
+	h, ok := s.handler(req.Method)
+	if !ok {
+		return nil, Errorf(CodeMethodNotFound, "unknown method %q", req.Method)
+	}
+	return h(req)
+}
+
+// serveLegacy answers the old STOP/STATUS/START verbs. Those clients write a
+// bare word without a trailing newline and wait for a single text reply.
+func (s *Server) serveLegacy(conn net.Conn, r *bufio.Reader) {
+	verb := readLegacyVerb(conn, r)
+
+	method, ok := legacyVerbs[verb]
+	if !ok {
+		_, _ = conn.Write([]byte("ERR: unknown command"))
+		return
+	}
+
+	result, err := s.call(&Request{Version: Version, Method: method})
+	if err != nil {
+		_, _ = conn.Write([]byte("ERR: " + toError(err).Message))
+		return
+	}
+
+	var msg string
+	switch v := result.(type) {
+	case string:
+		msg = v
+	case fmt.Stringer:
+		msg = v.String()
+	default:
+		data, _ := json.Marshal(v)
+		msg = string(data)
+	}
+	_, _ = conn.Write([]byte("OK: " + msg))
+}
+
+// readLegacyVerb reads a verb that may arrive over several writes. It stops
+// at a known verb, a newline, EOF or after legacyReadTimeout, whichever
+// comes first.
+func readLegacyVerb(conn net.Conn, r *bufio.Reader) string {
+	_ = conn.SetReadDeadline(time.Now().Add(legacyReadTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var buf []byte
+	for len(buf) < 64 {
+		if _, ok := legacyVerbs[strings.TrimSpace(string(buf))]; ok {
+			break
+		}
+		b, err := r.ReadByte()
+		if err != nil || b == '\n' {
+			break
+		}
+		buf = append(buf, b)
+	}
+	return strings.TrimSpace(string(buf))
+}
+
+func toError(err error) *Error {
+	var ipcErr *Error
+	if errors.As(err, &ipcErr) {
+		return ipcErr
+	}
+	return &Error{Code: CodeInternal, Message: err.Error()}
+}
@@ -0,0 +1,207 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serve starts a Server on the socket of a temporary runtime directory, so
+// Connect and Call reach it.
+func serve(t *testing.T) *Server {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	listener, err := net.Listen("unix", SocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	go s.Serve(listener)
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func TestCallRoundTrip(t *testing.T) {
+	s := serve(t)
+	s.Handle("echo", func(req *Request) (any, error) {
+		var params map[string]string
+		if err := req.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		return params, nil
+	})
+	s.Handle("busy", func(req *Request) (any, error) {
+		return nil, Errorf(CodeInvalidParams, "busy")
+	})
+	s.Handle("fail", func(req *Request) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	c, err := Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Several calls share the connection
+	for _, word := range []string{"one", "two"} {
+		var got map[string]string
+		if err := c.Call("echo", map[string]string{"word": word}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["word"] != word {
+			t.Errorf("echo = %v, want %s", got, word)
+		}
+	}
+
+	tests := []struct {
+		method string
+		params any
+		code   int
+	}{
+		{"missing", nil, CodeMethodNotFound},
+		{"busy", nil, CodeInvalidParams},
+		{"fail", nil, CodeInternal},
+		{"echo", []int{1}, CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		err := c.Call(tt.method, tt.params, nil)
+		var ipcErr *Error
+		if !errors.As(err, &ipcErr) || ipcErr.Code != tt.code {
+			t.Errorf("%s: err = %v, want code %d", tt.method, err, tt.code)
+		}
+	}
+}
+
+func TestMalformedRequests(t *testing.T) {
+	serve(t)
+	conn, err := Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	dec := json.NewDecoder(conn)
+	tests := []struct {
+		line string
+		code int
+	}{
+		{`{"v":1,"id":1,"method":`, CodeParseError},
+		{`{"v":99,"id":2,"method":"echo"}`, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		if _, err := io.WriteString(conn, tt.line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error == nil || resp.Error.Code != tt.code || resp.Version != Version {
+			t.Errorf("%s: response %+v, want code %d", tt.line, resp, tt.code)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := serve(t)
+	s.HandleStream("count", func(req *Request, send func(any) error, done <-chan struct{}) error {
+		for i := range 3 {
+			if err := send(i); err != nil {
+				return err
+			}
+		}
+		return Errorf(CodeInternal, "done")
+	})
+
+	c, err := Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var got []string
+	err = c.Stream("count", nil, func(event json.RawMessage) error {
+		got = append(got, string(event))
+		return nil
+	})
+	if strings.Join(got, ",") != "0,1,2" {
+		t.Errorf("events = %v", got)
+	}
+	var ipcErr *Error
+	if !errors.As(err, &ipcErr) || ipcErr.Message != "done" {
+		t.Errorf("stream ended with %v, want the handler's error", err)
+	}
+}
+
+func TestLegacyVerbs(t *testing.T) {
+	s := serve(t)
+	s.Handle("daemon.status", func(req *Request) (any, error) { return "running", nil })
+	s.Handle("daemon.stop", func(req *Request) (any, error) { return nil, errors.New("not now") })
+
+	legacy := func(writes ...string) string {
+		conn, err := Dial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		for i, w := range writes {
+			if i > 0 {
+				time.Sleep(20 * time.Millisecond)
+			}
+			io.WriteString(conn, w)
+		}
+		// The client keeps its end open waiting for the reply
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		reply, _ := io.ReadAll(conn)
+		return string(reply)
+	}
+
+	tests := []struct {
+		writes []string
+		want   string
+	}{
+		{[]string{"STATUS"}, "OK: running"},
+		{[]string{"STA", "TUS"}, "OK: running"},
+		{[]string{"S", "T", "ATUS\n"}, "OK: running"},
+		{[]string{"STOP"}, "ERR: not now"},
+		{[]string{"START"}, `ERR: unknown method "daemon.start"`},
+		{[]string{"HELLO"}, "ERR: unknown command"},
+	}
+	for _, tt := range tests {
+		if got := legacy(tt.writes...); got != tt.want {
+			t.Errorf("%q: reply %q, want %q", tt.writes, got, tt.want)
+		}
+	}
+}
+
+// failingListener fails Accept a number of times before it is closed.
+type failingListener struct {
+	net.Listener
+	fails atomic.Int32
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	if l.fails.Add(-1) >= 0 {
+		return nil, errors.New("too many open files")
+	}
+	return nil, net.ErrClosed
+}
+
+func TestServeBacksOffOnAcceptErrors(t *testing.T) {
+	l := &failingListener{}
+	l.fails.Store(4)
+	start := time.Now()
+	if err := NewServer().Serve(l); err != nil {
+		t.Fatal(err)
+	}
+	// 5, 10, 20 and 40ms
+	if elapsed := time.Since(start); elapsed < 75*time.Millisecond {
+		t.Errorf("Serve retried after %v, want a back off", elapsed)
+	}
+}
@@ -2,11 +2,13 @@ package manager
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/hoppxi/wigo/internal/watchers"
 	"github.com/spf13/viper"
 )
 
@@ -19,16 +21,20 @@ type ConfigManager struct{}
 
 var Config = &ConfigManager{}
 
+// ewwConfigDir is where eww.yuck, eww.scss and wigo.yaml live.
+func ewwConfigDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return filepath.Join(configDir, "eww")
+}
+
 func (c *ConfigManager) Load() *viper.Viper {
 	once.Do(func() {
 		v = viper.New()
 
-		configDir, err := os.UserConfigDir()
-		if err != nil {
-			panic(err)
-		}
-
-		confPath := filepath.Join(configDir, "eww", "wigo.yaml")
+		confPath := filepath.Join(ewwConfigDir(), "wigo.yaml")
 
 		v.SetConfigFile(confPath)
 		v.SetConfigType("yaml")
@@ -41,6 +47,40 @@ func (c *ConfigManager) Load() *viper.Viper {
 	return v
 }
 
+// Reload reads wigo.yaml again. On error the previous values are kept.
+func (c *ConfigManager) Reload() (*viper.Viper, error) {
+	cfg := c.Load()
+	if err := cfg.ReadInConfig(); err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+	return cfg, nil
+}
+
+// WatcherOptions returns the `watchers:` section of wigo.yaml keyed by
+// watcher name.
+func (c *ConfigManager) WatcherOptions() map[string]watchers.Options {
+	opts := map[string]watchers.Options{}
+	if err := c.Load().UnmarshalKey("watchers", &opts); err != nil {
+		log.Printf("invalid watchers config: %v", err)
+	}
+	return opts
+}
+
+// NotificationSettings returns the `notifications:` section of wigo.yaml.
+func (c *ConfigManager) NotificationSettings() watchers.NotificationSettings {
+	var s watchers.NotificationSettings
+	if err := c.Load().UnmarshalKey("notifications", &s); err != nil {
+		log.Printf("invalid notifications config: %v", err)
+	}
+	return s
+}
+
+// Apply hands the watcher and notification settings to the watchers.
+func (c *ConfigManager) Apply() {
+	watchers.Configure(c.WatcherOptions())
+	watchers.ConfigureNotifications(c.NotificationSettings())
+}
+
 func (c *ConfigManager) Watch(onChange func()) {
 	v.WatchConfig()
 	v.OnConfigChange(func(e fsnotify.Event) {
@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/widgets"
+)
+
+// ewwReadyTimeout bounds how long a restarted eww gets to answer `eww ping`.
+const ewwReadyTimeout = 5 * time.Second
+
+func ewwDaemonCmd() (*exec.Cmd, context.CancelFunc) {
+	return NewCmd("eww", "daemon", "--no-daemonize")
+}
+
+// StartEww runs `eww daemon` and keeps it running. When it dies it is started
+// again with backoff, the permanent widgets are reopened and every variable is
+// replayed so the widgets come back in the state they were in.
+func (m *AppManager) StartEww() error {
+	t, err := m.startTracked(ewwDaemonCmd())
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	restart := make(chan struct{}, 1)
+	m.mu.Lock()
+	m.stops = append(m.stops, stop)
+	m.ewwRestart = restart
+	m.ewwHash = ewwConfigHash()
+	m.mu.Unlock()
+
+	go m.superviseEww(t, stop, restart)
+	return nil
+}
+
+// restartEww asks the eww supervisor to start eww over right away.
+func (m *AppManager) restartEww() {
+	m.mu.Lock()
+	restart := m.ewwRestart
+	m.mu.Unlock()
+	if restart == nil {
+		return
+	}
+	select {
+	case restart <- struct{}{}:
+	default:
+	}
+}
+
+func (m *AppManager) superviseEww(t *TrackedCmd, stop <-chan struct{}, restart <-chan struct{}) {
+	backoff := minBackoff
+	for {
+		requested := false
+		select {
+		case <-stop:
+			return
+		case <-restart:
+			log.Println("Restarting eww daemon...")
+			t.kill()
+			requested = true
+		case <-t.done:
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if !requested {
+			if time.Since(t.Started) >= stableRun {
+				backoff = minBackoff
+			}
+			log.Printf("eww daemon exited: %v. Restarting in %s...", t.err, backoff)
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+
+		next, err := m.startTracked(ewwDaemonCmd())
+		if err != nil {
+			log.Printf("Failed to restart eww daemon: %v", err)
+			continue
+		}
+		t = next
+
+		// StopAll may have run while eww was starting
+		select {
+		case <-stop:
+			t.Cancel()
+			return
+		default:
+		}
+
+		if err := m.restoreEww(); err != nil {
+			log.Printf("Failed to restore eww: %v", err)
+		}
+	}
+}
+
+// restoreEww waits for a fresh eww daemon to answer, then reopens the
+// permanent widgets and replays the last value of every variable.
+func (m *AppManager) restoreEww() error {
+	if err := waitEww(ewwReadyTimeout); err != nil {
+		return err
+	}
+
+	widgets.Forget()
+	if err := widgets.OpenPermanent(); err != nil {
+		return err
+	}
+
+	return m.replayEww()
+}
+
+// replayEww pushes the cached state to eww again, including values eww is
+// believed to already have.
+func (m *AppManager) replayEww() error {
+	m.mu.Lock()
+	s, state := m.ewwSink, m.state
+	m.mu.Unlock()
+	if s == nil || state == nil {
+		return nil
+	}
+
+	s.Reset()
+	if err := state.Replay(s); err != nil {
+		return err
+	}
+	return s.Flush()
+}
+
+// ewwConfigHash fingerprints eww.yuck, eww.scss and everything under src, so a
+// reload can tell whether eww has to be restarted.
+func ewwConfigHash() string {
+	dir := ewwConfigDir()
+	h := sha256.New()
+
+	add := func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		rel, _ := filepath.Rel(dir, path)
+		fmt.Fprintf(h, "%s %d\n", rel, len(data))
+		h.Write(data)
+	}
+
+	add(filepath.Join(dir, "eww.yuck"))
+	add(filepath.Join(dir, "eww.scss"))
+	filepath.WalkDir(filepath.Join(dir, "src"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			add(path)
+		}
+		return nil
+	})
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func waitEww(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if exec.Command("eww", "ping").Run() == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return errors.New("eww daemon did not answer ping")
+}
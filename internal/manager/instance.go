@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hoppxi/wigo/internal/ipc"
+)
+
+// AlreadyRunningError is returned by Lock when another daemon holds the lock.
+type AlreadyRunningError struct {
+	PID int
+}
+
+func (e *AlreadyRunningError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("another wigo daemon is already running (pid %d)", e.PID)
+	}
+	return "another wigo daemon is already running"
+}
+
+func lockPath() (string, error) {
+	dir, err := ipc.RuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.lock"), nil
+}
+
+// Lock makes this process the only daemon for the user. The lock file also
+// holds the daemon pid and is released by the kernel when the process exits,
+// however it exits.
+func (m *AppManager) Lock() error {
+	path, err := lockPath()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			pid, _ := LockedPID()
+			return &AlreadyRunningError{PID: pid}
+		}
+		return err
+	}
+
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	m.mu.Lock()
+	m.lock = f
+	m.mu.Unlock()
+	return nil
+}
+
+// LockedPID returns the pid of the daemon holding the lock, or 0 when no
+// daemon holds it.
+func LockedPID() (int, error) {
+	path, err := lockPath()
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	// Taking a shared lock succeeds only if no daemon holds it
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// DaemonHint explains why the daemon cannot be reached and what to do.
+func DaemonHint() (problem, hint string) {
+	pid, _ := LockedPID()
+	switch ipc.Probe() {
+	case ipc.SocketLive:
+		return "", ""
+	case ipc.SocketStale:
+		if pid > 0 {
+			return fmt.Sprintf("daemon (pid %d) is not answering on %s", pid, ipc.SocketPath()),
+				fmt.Sprintf("it may be stuck, run `kill %d` and then `wigo start`", pid)
+		}
+		return fmt.Sprintf("stale socket %s left by a daemon that exited", ipc.SocketPath()),
+			"run `wigo start`, it removes the stale socket"
+	}
+	if pid > 0 {
+		return fmt.Sprintf("daemon (pid %d) is running but has no socket yet", pid),
+			"wait for it to finish starting or check its log"
+	}
+	return "daemon is not running", "run `wigo start` first"
+}
@@ -0,0 +1,390 @@
+package manager
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/bus"
+	"github.com/hoppxi/wigo/internal/ipc"
+	"github.com/hoppxi/wigo/internal/sink"
+	"github.com/hoppxi/wigo/internal/wallpaper"
+	"github.com/hoppxi/wigo/internal/watchers"
+	"github.com/hoppxi/wigo/internal/widgets"
+	"github.com/hoppxi/wigo/pkg/search"
+)
+
+type DaemonStatus struct {
+	State     string        `json:"state"`
+	PID       int           `json:"pid"`
+	Started   bool          `json:"started"`
+	StartedAt time.Time     `json:"started_at,omitzero"`
+	Watchers  []WatcherInfo `json:"watchers"`
+	Processes []ProcessInfo `json:"processes"`
+}
+
+// String keeps the reply of the legacy STATUS verb unchanged.
+func (s DaemonStatus) String() string {
+	return s.State
+}
+
+type widgetParams struct {
+	Widget string `json:"widget"`
+}
+
+type subscribeParams struct {
+	Topics []string `json:"topics"`
+	// Replay sends the last value of every matching variable first.
+	Replay bool `json:"replay"`
+}
+
+type stateParams struct {
+	Name string `json:"name"`
+}
+
+type searchParams struct {
+	Term  string `json:"term"`
+	Limit int    `json:"limit"`
+}
+
+func (m *AppManager) StartIPCServer() {
+	socketPath := ipc.SocketPath()
+	switch ipc.Probe() {
+	case ipc.SocketLive:
+		log.Fatalf("Another daemon is listening on %s", socketPath)
+	case ipc.SocketStale:
+		log.Printf("Removing stale socket %s", socketPath)
+		_ = os.Remove(socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		log.Fatalf("Error listening on socket: %v", err)
+	}
+	defer listener.Close()
+
+	m.mu.Lock()
+	m.listener = listener
+	m.mu.Unlock()
+
+	// This process is the daemon now; events forwarded over IPC would come
+	// straight back to it
+	serving.Store(true)
+	bus.Default.SetForwarder(nil)
+
+	log.Printf("IPC Server listening on: %s", socketPath)
+
+	m.server = ipc.NewServer()
+	m.registerMethods(m.server)
+	m.startAppIndex()
+
+	if err := m.server.Serve(listener); err != nil {
+		log.Printf("IPC server stopped: %v", err)
+	}
+}
+
+func (m *AppManager) registerMethods(s *ipc.Server) {
+	s.Handle("daemon.status", func(req *ipc.Request) (any, error) {
+		return m.Status(), nil
+	})
+
+	s.Handle("daemon.stop", func(req *ipc.Request) (any, error) {
+		log.Println("Received daemon.stop via IPC. Shutting down...")
+		go func() {
+			// Give the reply a moment to reach the client
+			time.Sleep(50 * time.Millisecond)
+			m.StopAll()
+			time.Sleep(200 * time.Millisecond)
+			os.Exit(0)
+		}()
+		return "Shutting down.", nil
+	})
+
+	s.Handle("daemon.start", func(req *ipc.Request) (any, error) {
+		if !m.start() {
+			return "Already started", nil
+		}
+		return "Starting", nil
+	})
+
+	s.Handle("daemon.reload", func(req *ipc.Request) (any, error) {
+		if err := m.Reload(); err != nil {
+			return nil, err
+		}
+		return "Reloaded", nil
+	})
+
+	s.Handle("widget.open", m.widgetMethod(widgets.Open))
+	s.Handle("widget.close", m.widgetMethod(widgets.Close))
+	s.Handle("widget.toggle", m.widgetMethod(widgets.Toggle))
+
+	s.Handle("watcher.status", func(req *ipc.Request) (any, error) {
+		return m.WatcherStatus(), nil
+	})
+
+	s.Handle("state.get", func(req *ipc.Request) (any, error) {
+		var p stateParams
+		if err := req.DecodeParams(&p); err != nil {
+			return nil, err
+		}
+		if p.Name == "" {
+			return nil, ipc.Errorf(ipc.CodeInvalidParams, "missing name")
+		}
+		state := m.State()
+		if state == nil {
+			return nil, ipc.Errorf(ipc.CodeInternal, "no state cache")
+		}
+		u, ok := state.Get(p.Name)
+		if !ok {
+			return nil, ipc.Errorf(ipc.CodeInvalidParams, "unknown variable %s", p.Name)
+		}
+		return u.Value, nil
+	})
+
+	s.Handle("state.dump", func(req *ipc.Request) (any, error) {
+		vars := make(map[string]any)
+		if state := m.State(); state != nil {
+			for _, u := range state.Updates() {
+				vars[u.Name] = u.Value
+			}
+		}
+		return vars, nil
+	})
+
+	s.Handle("state.replay", func(req *ipc.Request) (any, error) {
+		if err := m.replayEww(); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	})
+
+	s.Handle("search.query", func(req *ipc.Request) (any, error) {
+		var p searchParams
+		if err := req.DecodeParams(&p); err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		apps := m.apps
+		m.mu.Unlock()
+		if apps == nil {
+			return nil, ipc.Errorf(ipc.CodeInternal, "no app index")
+		}
+		results := apps.Search(p.Term)
+		if p.Limit > 0 && len(results) > p.Limit {
+			results = results[:p.Limit]
+		}
+		return results, nil
+	})
+
+	s.Handle("events.publish", func(req *ipc.Request) (any, error) {
+		var e bus.Event
+		if err := req.DecodeParams(&e); err != nil {
+			return nil, err
+		}
+		if e.Topic == "" {
+			return nil, ipc.Errorf(ipc.CodeInvalidParams, "missing topic")
+		}
+		bus.Default.Send(e)
+		return "ok", nil
+	})
+
+	s.Handle("sink.publish", func(req *ipc.Request) (any, error) {
+		var u sink.Update
+		if err := req.DecodeParams(&u); err != nil {
+			return nil, err
+		}
+		if u.Name == "" {
+			return nil, ipc.Errorf(ipc.CodeInvalidParams, "missing name")
+		}
+		if err := sink.Default.Send(u); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	})
+
+	s.HandleStream("events.subscribe", func(req *ipc.Request, send func(any) error, done <-chan struct{}) error {
+		var p subscribeParams
+		if err := req.DecodeParams(&p); err != nil {
+			return err
+		}
+
+		sub := bus.Subscribe(p.Topics)
+		defer sub.Close()
+
+		if state := m.State(); p.Replay && state != nil {
+			now := time.Now().UnixMilli()
+			for _, u := range state.Updates() {
+				if !sub.Wants(u.Name) {
+					continue
+				}
+				if err := send(bus.Event{Topic: u.Name, Data: u.Value, Time: now}); err != nil {
+					return nil
+				}
+			}
+		}
+
+		for {
+			select {
+			case <-done:
+				return nil
+			case e := <-sub.C:
+				if err := send(e); err != nil {
+					return nil
+				}
+			}
+		}
+	})
+}
+
+// startAppIndex builds the desktop app index and keeps it fresh for the
+// daemon's lifetime.
+func (m *AppManager) startAppIndex() {
+	apps := search.NewAppIndex()
+	stop := make(chan struct{})
+	m.mu.Lock()
+	m.apps = apps
+	m.stops = append(m.stops, stop)
+	m.mu.Unlock()
+
+	go func() {
+		if err := apps.Run(stop); err != nil {
+			log.Printf("app index: %v", err)
+		}
+	}()
+}
+
+// serving is set once this process runs the IPC server.
+var serving atomic.Bool
+
+// ForwardEvents hands every event and variable published in this process
+// over to the running daemon, so CLI commands such as `wigo notification` or
+// `wigo wallpaper` go through the daemon sinks and reach its subscribers.
+func ForwardEvents() {
+	if serving.Load() {
+		return
+	}
+	bus.Default.SetForwarder(func(e bus.Event) {
+		_ = ipc.Call("events.publish", e, nil)
+	})
+	sink.Default.Set(sink.NewForward(sink.NewEww(0)))
+}
+
+func (m *AppManager) widgetMethod(f func(widget string) error) ipc.HandlerFunc {
+	return func(req *ipc.Request) (any, error) {
+		var p widgetParams
+		if err := req.DecodeParams(&p); err != nil {
+			return nil, err
+		}
+		if p.Widget == "" {
+			return nil, ipc.Errorf(ipc.CodeInvalidParams, "missing widget")
+		}
+		if err := f(p.Widget); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	}
+}
+
+// start initializes watchers and widgets once per daemon lifetime.
+func (m *AppManager) start() bool {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return false
+	}
+	m.started = true
+	m.startedAt = time.Now()
+	m.mu.Unlock()
+
+	log.Println("Initializing watchers and widgets...")
+
+	cfg := Config.Load()
+	Config.Apply()
+	wallpaper.SetWallpaperStartup()
+	watchers.ConfigUpdate(cfg)
+	Config.Watch(func() {
+		Config.Apply()
+		watchers.ConfigUpdate(cfg)
+	})
+
+	m.StartWatchers()
+
+	if m.UsesEww() {
+		if err := widgets.OpenPermanent(); err != nil {
+			log.Printf("Failed to start widgets: %v", err)
+		}
+	}
+	return true
+}
+
+// Reload stops the watchers, reads wigo.yaml again and brings watchers and
+// widgets back up inside the running daemon. eww itself is only restarted
+// when eww.yuck, eww.scss or src changed.
+func (m *AppManager) Reload() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.mu.Lock()
+	started := m.started
+	m.mu.Unlock()
+	if !started {
+		return errors.New("daemon is not started")
+	}
+
+	log.Println("Reloading watchers and widgets...")
+	m.stopWatchers()
+
+	cfg, err := Config.Reload()
+	if err != nil {
+		log.Printf("Keeping previous config: %v", err)
+	}
+	Config.Apply()
+	watchers.ConfigUpdate(cfg)
+
+	m.StartWatchers()
+
+	if m.UsesEww() {
+		hash := ewwConfigHash()
+		m.mu.Lock()
+		changed := hash != m.ewwHash
+		m.ewwHash = hash
+		m.mu.Unlock()
+
+		if changed {
+			// The eww supervisor reopens the widgets and replays the state
+			m.restartEww()
+		} else {
+			if err := widgets.OpenPermanent(); err != nil {
+				log.Printf("Failed to open widgets: %v", err)
+			}
+			if err := m.replayEww(); err != nil {
+				log.Printf("Failed to replay state: %v", err)
+			}
+		}
+	}
+	return err
+}
+
+// Status reports the daemon, its watchers and its child processes.
+func (m *AppManager) Status() DaemonStatus {
+	m.mu.Lock()
+	st := DaemonStatus{State: "running", PID: os.Getpid(), Started: m.started, StartedAt: m.startedAt}
+	m.mu.Unlock()
+
+	st.Watchers = m.WatcherStatus()
+	st.Processes = m.ProcessStatus()
+	return st
+}
+
+func (m *AppManager) ConnectIPC() (net.Conn, error) {
+	return ipc.Dial()
+}
+
+// SendIPCCommand calls method on the running daemon and decodes the result
+// into result when it is non-nil.
+func (m *AppManager) SendIPCCommand(method string, params any, result any) error {
+	return ipc.Call(method, params, result)
+}
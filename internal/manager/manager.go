@@ -2,51 +2,73 @@ package manager
 
 import (
 	"context"
-	"io"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/hoppxi/wigo/internal/wallpaper"
-	"github.com/hoppxi/wigo/internal/watchers"
+	"github.com/hoppxi/wigo/internal/ipc"
+	"github.com/hoppxi/wigo/internal/sink"
+	"github.com/hoppxi/wigo/pkg/search"
 )
 
 type TrackedCmd struct {
-	Cmd    *exec.Cmd
-	Cancel context.CancelFunc
+	Name    string
+	Cmd     *exec.Cmd
+	Cancel  context.CancelFunc
+	Started time.Time
+	// Restarts counts how often a supervised process was started again.
+	Restarts int
+
+	// done is closed once the process has been reaped, err holds what Wait
+	// returned.
+	done chan struct{}
+	err  error
+}
+
+type ProcessInfo struct {
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Restarts  int       `json:"restarts"`
 }
 
 type AppManager struct {
-	mu      sync.Mutex
-	cmds    []TrackedCmd
-	stops   []chan struct{}
-	wg      sync.WaitGroup
+	mu       sync.Mutex
+	cmds     []*TrackedCmd
+	stops    []chan struct{}
+	watchers []*WatcherInfo
+	// leaked holds watchers that did not stop in time, by name; they are
+	// not started again while they still run.
+	leaked  map[string]*WatcherInfo
 	started bool
+	// startedAt is when watchers and widgets were brought up.
+	startedAt time.Time
+	eww       bool
+	ewwSink   *sink.Eww
+	// ewwRestart asks the eww supervisor for a restart, ewwHash fingerprints
+	// the eww config it was started with.
+	ewwRestart chan struct{}
+	ewwHash    string
+	reloadMu   sync.Mutex
+	// lock is the open daemon.lock file, held for the process lifetime.
+	lock *os.File
+	// listener is closed on shutdown, which also removes the socket file.
+	listener net.Listener
+	state    *sink.State
+	server   *ipc.Server
+	// apps is the desktop app index behind search.query.
+	apps *search.AppIndex
 }
 
 var Manage = &AppManager{}
 
-func getSocketPath() string {
-	var baseDir string
-	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
-		baseDir = runtimeDir
-	} else {
-		baseDir = os.TempDir()
-	}
-
-	socketDir := filepath.Join(baseDir, "wigo")
-	if err := os.MkdirAll(socketDir, 0o755); err != nil {
-		return filepath.Join(os.TempDir(), "wigo-socket.sock")
-	}
-	return filepath.Join(socketDir, "socket.sock")
-}
-
 func NewCmd(command string, args ...string) (*exec.Cmd, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cmd := exec.CommandContext(ctx, command, args...)
@@ -56,195 +78,121 @@ func NewCmd(command string, args ...string) (*exec.Cmd, context.CancelFunc) {
 }
 
 func StartTrackedCmd(cmd *exec.Cmd, cancel context.CancelFunc) *exec.Cmd {
-	if err := cmd.Start(); err != nil {
-		cancel()
+	if _, err := Manage.startTracked(cmd, cancel); err != nil {
 		log.Printf("Failed to start %s: %v", cmd.Args[0], err)
 		return nil
 	}
-
-	Manage.mu.Lock()
-	Manage.cmds = append(Manage.cmds, TrackedCmd{Cmd: cmd, Cancel: cancel})
-	Manage.mu.Unlock()
-
 	return cmd
 }
 
-func (m *AppManager) StartIPCServer() {
-	socketPath := getSocketPath()
-	_ = os.Remove(socketPath)
+// startTracked starts cmd and reaps it in the background. A previous process
+// with the same name is replaced in the list and its restart count carried
+// over.
+func (m *AppManager) startTracked(cmd *exec.Cmd, cancel context.CancelFunc) (*TrackedCmd, error) {
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
 
-	listener, err := net.Listen("unix", socketPath)
-	if err != nil {
-		log.Fatalf("Error listening on socket: %v", err)
+	t := &TrackedCmd{
+		Name:    filepath.Base(cmd.Args[0]),
+		Cmd:     cmd,
+		Cancel:  cancel,
+		Started: time.Now(),
+		done:    make(chan struct{}),
 	}
-	defer listener.Close()
 
-	log.Printf("IPC Server listening on: %s", socketPath)
+	m.mu.Lock()
+	replaced := false
+	for i, old := range m.cmds {
+		if old.Name == t.Name {
+			t.Restarts = old.Restarts + 1
+			m.cmds[i] = t
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		m.cmds = append(m.cmds, t)
+	}
+	m.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		m.mu.Lock()
+		t.err = err
+		m.mu.Unlock()
+		close(t.done)
+	}()
+
+	return t, nil
+}
 
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			if strings.Contains(err.Error(), "use of closed network connection") {
-				return
+// ProcessStatus reports every child process the daemon started.
+func (m *AppManager) ProcessStatus() []ProcessInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]ProcessInfo, 0, len(m.cmds))
+	for _, t := range m.cmds {
+		p := ProcessInfo{Name: t.Name, PID: t.Cmd.Process.Pid, State: "running", StartedAt: t.Started, Restarts: t.Restarts}
+		select {
+		case <-t.done:
+			p.State = "exited"
+			if t.err != nil {
+				p.Error = t.err.Error()
 			}
-			continue
+		default:
 		}
-		go m.handleConnection(conn)
+		out = append(out, p)
 	}
+	return out
 }
 
-func (m *AppManager) handleConnection(conn net.Conn) {
-	defer conn.Close()
-
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil {
+// kill terminates the process group of t and waits until it is reaped.
+func (t *TrackedCmd) kill() {
+	if t.Cmd == nil || t.Cmd.Process == nil {
 		return
 	}
 
-	command := strings.TrimSpace(string(buf[:n]))
-
-	switch command {
-
-	case "STOP":
-		log.Println("Received STOP via IPC. Shutting down...")
-		_, _ = conn.Write([]byte("OK: Shutting down."))
-
-		// Close immediately so client doesn't hang
-		_ = conn.Close()
-
-		go func() {
-			m.StopAll()
-			time.Sleep(200 * time.Millisecond)
-			os.Exit(0)
-		}()
-
-	case "STATUS":
-		_, _ = conn.Write([]byte("OK: running"))
-	case "START":
-		m.mu.Lock()
-		if m.started {
-			m.mu.Unlock()
-			_, _ = conn.Write([]byte("OK: Already started"))
-			return
-		}
-		m.started = true
-		m.mu.Unlock()
+	pid := t.Cmd.Process.Pid
+	pgid, err := syscall.Getpgid(pid)
 
-		log.Println("Received START via IPC. Initializing watchers and widgets...")
-		_, _ = conn.Write([]byte("OK: Starting"))
-
-		cfg := Config.Load()
-		wallpaper.SetWallpaperStartup()
-		watchers.ConfigUpdate(cfg)
-		Config.Watch(func() {
-			watchers.ConfigUpdate(cfg)
-		})
-
-		Manage.StartWatcher(watchers.StartAudioWatcher)
-		Manage.StartWatcher(watchers.StartBatteryWatcher)
-		Manage.StartWatcher(watchers.StartNetworkWatcher)
-		Manage.StartWatcher(watchers.StartBluetoothWatcher)
-		Manage.StartWatcher(watchers.StartDisplayWatcher)
-		Manage.StartWatcher(watchers.StartMediaWatcher)
-		Manage.StartWatcher(watchers.StartWorkspaceWatcher)
-		Manage.StartWatcher(watchers.StartMiscWatcher)
-		Manage.StartWatcher(watchers.StartEscWatcher)
-		Manage.StartWatcher(watchers.StartLEDsWatcher)
-
-		if err := exec.Command("eww", "open-many", "bar", "wallpaper", "clock", "notification-view", "osd").Run(); err != nil {
-			log.Printf("Failed to start widgets: %v", err)
-		}
+	if err == nil {
+		_ = syscall.Kill(-pgid, syscall.SIGTERM)
 
-	default:
-		_, _ = conn.Write([]byte("ERR: unknown command"))
+		time.Sleep(50 * time.Millisecond)
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
 	}
-}
 
-func (m *AppManager) StartWatcher(f func(stop <-chan struct{})) {
-	stop := make(chan struct{})
-	m.mu.Lock()
-	m.stops = append(m.stops, stop)
-	m.mu.Unlock()
-
-	m.wg.Add(1)
-	go func() {
-		defer m.wg.Done()
-		for {
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("Watcher panic: %v", r)
-					}
-				}()
-				f(stop)
-			}()
-
-			select {
-			case <-stop:
-				return
-			case <-time.After(2 * time.Second):
-				log.Println("Restarting watcher...")
-			}
-		}
-	}()
+	if t.Cancel != nil {
+		t.Cancel()
+	}
+	_ = t.Cmd.Process.Kill()
+	<-t.done
 }
 
 func (m *AppManager) StopAll() {
+	m.stopWatchers()
+
 	m.mu.Lock()
 	cmds := m.cmds
 	stops := m.stops
+	listener := m.listener
 	m.cmds = nil
 	m.stops = nil
+	m.listener = nil
 	m.mu.Unlock()
 
+	if listener != nil {
+		listener.Close()
+	}
+
 	for _, s := range stops {
 		close(s)
 	}
 
 	for _, t := range cmds {
-		if t.Cancel != nil {
-			t.Cancel()
-		}
-		if t.Cmd == nil || t.Cmd.Process == nil {
-			continue
-		}
-
-		pid := t.Cmd.Process.Pid
-		pgid, err := syscall.Getpgid(pid)
-
-		if err == nil {
-			_ = syscall.Kill(-pgid, syscall.SIGTERM)
-
-			time.Sleep(50 * time.Millisecond)
-			_ = syscall.Kill(-pgid, syscall.SIGKILL)
-		}
-
-		_ = t.Cmd.Process.Kill()
-		_ = t.Cmd.Wait()
-	}
-}
-
-func (m *AppManager) ConnectIPC() (net.Conn, error) {
-	return net.DialTimeout("unix", getSocketPath(), 500*time.Millisecond)
-}
-
-func (m *AppManager) SendIPCCommand(cmd string) (string, error) {
-	conn, err := m.ConnectIPC()
-	if err != nil {
-		return "", err
-	}
-	defer conn.Close()
-
-	if _, err := conn.Write([]byte(cmd)); err != nil {
-		return "", err
-	}
-
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil && err != io.EOF {
-		return "", err
+		t.kill()
 	}
-
-	return string(buf[:n]), nil
 }
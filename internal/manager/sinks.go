@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hoppxi/wigo/internal/bus"
+	"github.com/hoppxi/wigo/internal/sink"
+)
+
+// UseSinks sets up the frontends the daemon publishes to. IPC subscribers
+// and the state cache are always served; names may contain "eww" and
+// "stdout".
+func (m *AppManager) UseSinks(names []string) error {
+	state := sink.NewState()
+	sinks := []sink.Sink{state, sink.NewStream(bus.Default)}
+	var ewwSink *sink.Eww
+	for _, name := range names {
+		switch name {
+		case "eww":
+			ewwSink = sink.NewEww(sink.FlushInterval)
+			sinks = append(sinks, ewwSink)
+		case "stdout":
+			sinks = append(sinks, sink.NewStdout())
+		default:
+			return fmt.Errorf("unknown sink %q (use eww or stdout)", name)
+		}
+	}
+
+	m.mu.Lock()
+	m.eww = slices.Contains(names, "eww")
+	m.ewwSink = ewwSink
+	m.state = state
+	m.mu.Unlock()
+
+	sink.Default.Set(sinks...)
+	return nil
+}
+
+// State returns the last-known value cache, nil outside the daemon.
+func (m *AppManager) State() *sink.State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// UsesEww reports whether eww is one of the daemon sinks.
+func (m *AppManager) UsesEww() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.eww
+}
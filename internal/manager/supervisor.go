@@ -0,0 +1,242 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/watchers"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// A run at least this long counts as healthy and resets the backoff.
+	stableRun = 30 * time.Second
+	// A run shorter than this counts as a rapid crash.
+	rapidCrash = 10 * time.Second
+	// After this many rapid crashes in a row the watcher is given up on.
+	maxRapidCrashes = 5
+)
+
+// stopTimeout bounds how long stopping waits for watchers to return.
+var stopTimeout = 5 * time.Second
+
+type WatcherInfo struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Restarts  int       `json:"restarts"`
+	Error     string    `json:"error,omitempty"`
+	StartedAt time.Time `json:"started_at,omitzero"`
+	LastEvent time.Time `json:"last_event,omitzero"`
+
+	spec watchers.Spec
+	stop chan struct{}
+	// done is closed once the watcher and its restart loop returned.
+	done chan struct{}
+}
+
+// StartWatcher runs spec.Start and restarts it with exponential backoff each
+// time it returns or panics. A watcher crashing quickly maxRapidCrashes times
+// in a row is marked failed and left alone until the daemon is reloaded.
+func (m *AppManager) StartWatcher(spec watchers.Spec) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	info := &WatcherInfo{Name: spec.Name, State: "starting", spec: spec, stop: stop, done: done}
+	m.mu.Lock()
+	m.watchers = append(m.watchers, info)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		backoff := minBackoff
+		rapid := 0
+		for {
+			started := time.Now()
+			m.mu.Lock()
+			info.State = "running"
+			info.StartedAt = started
+			m.mu.Unlock()
+
+			err := runWatcher(spec, stop)
+
+			select {
+			case <-stop:
+				m.setWatcherState(info, "stopped")
+				return
+			default:
+			}
+
+			if err == nil {
+				err = errors.New("watcher returned")
+			}
+			ran := time.Since(started)
+			if ran >= stableRun {
+				backoff = minBackoff
+			}
+			if ran < rapidCrash {
+				rapid++
+			} else {
+				rapid = 0
+			}
+
+			m.mu.Lock()
+			info.Error = err.Error()
+			if rapid >= maxRapidCrashes {
+				info.State = "failed"
+			} else {
+				info.State = "backoff"
+			}
+			m.mu.Unlock()
+
+			if rapid >= maxRapidCrashes {
+				log.Printf("Watcher %s failed %d times in a row, giving up: %v", spec.Name, rapid, err)
+				<-stop
+				m.setWatcherState(info, "stopped")
+				return
+			}
+
+			log.Printf("Watcher %s exited: %v. Restarting in %s...", spec.Name, err, backoff)
+			select {
+			case <-stop:
+				m.setWatcherState(info, "stopped")
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+
+			m.mu.Lock()
+			info.Restarts++
+			m.mu.Unlock()
+		}
+	}()
+}
+
+// runWatcher turns a panic into an error so it is recorded like any crash.
+func runWatcher(spec watchers.Spec, stop <-chan struct{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return spec.Start(stop)
+}
+
+// StartWatchers starts every registered watcher that is enabled in wigo.yaml
+// and can work on this machine.
+func (m *AppManager) StartWatchers() {
+	for _, spec := range watchers.Registry {
+		opts := watchers.OptionsFor(spec.Name)
+		if !opts.EnabledFor(spec) {
+			m.recordWatcher(spec.Name, "disabled", "")
+			continue
+		}
+		if w := m.stillRunning(spec.Name); w != nil {
+			log.Printf("Not starting watcher %s, the previous one is still running", spec.Name)
+			m.mu.Lock()
+			m.watchers = append(m.watchers, w)
+			m.mu.Unlock()
+			continue
+		}
+		if spec.Available != nil && !opts.Force {
+			if err := spec.Available(); err != nil {
+				log.Printf("Skipping watcher %s: %v", spec.Name, err)
+				m.recordWatcher(spec.Name, "unavailable", err.Error())
+				continue
+			}
+		}
+		m.StartWatcher(spec)
+	}
+}
+
+// stopWatchers stops every watcher and waits up to stopTimeout for them to
+// return. Those still running afterwards are marked leaked.
+func (m *AppManager) stopWatchers() {
+	m.mu.Lock()
+	var infos []*WatcherInfo
+	for _, w := range m.watchers {
+		// A leaked watcher was told to stop already and is not waited for
+		// again
+		if w.done != nil && m.leaked[w.Name] != w {
+			close(w.stop)
+			infos = append(infos, w)
+		}
+	}
+	m.watchers = nil
+	m.mu.Unlock()
+
+	timeout := time.NewTimer(stopTimeout)
+	defer timeout.Stop()
+	expired := false
+	for _, w := range infos {
+		if !expired {
+			select {
+			case <-w.done:
+				continue
+			case <-timeout.C:
+				expired = true
+			}
+		}
+		select {
+		case <-w.done:
+		default:
+			log.Printf("Watcher %s did not stop within %s, it is not restarted until it does", w.Name, stopTimeout)
+			m.mu.Lock()
+			if w.State != "leaked" {
+				w.State = "leaked"
+				w.Error = fmt.Sprintf("did not stop within %s", stopTimeout)
+			}
+			if m.leaked == nil {
+				m.leaked = make(map[string]*WatcherInfo)
+			}
+			m.leaked[w.Name] = w
+			m.mu.Unlock()
+		}
+	}
+}
+
+// stillRunning returns the leaked watcher name if it has not returned yet.
+func (m *AppManager) stillRunning(name string) *WatcherInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.leaked[name]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-w.done:
+		delete(m.leaked, name)
+		return nil
+	default:
+		return w
+	}
+}
+
+// recordWatcher lists a watcher that is not running in the status report.
+func (m *AppManager) recordWatcher(name, state, reason string) {
+	m.mu.Lock()
+	m.watchers = append(m.watchers, &WatcherInfo{Name: name, State: state, Error: reason})
+	m.mu.Unlock()
+}
+
+func (m *AppManager) setWatcherState(info *WatcherInfo, state string) {
+	m.mu.Lock()
+	info.State = state
+	m.mu.Unlock()
+}
+
+// WatcherStatus returns a snapshot of every watcher started so far.
+func (m *AppManager) WatcherStatus() []WatcherInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]WatcherInfo, 0, len(m.watchers))
+	for _, w := range m.watchers {
+		info := *w
+		info.LastEvent = w.spec.LastEvent()
+		out = append(out, info)
+	}
+	return out
+}
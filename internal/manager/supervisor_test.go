@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/watchers"
+)
+
+func waitState(t *testing.T, m *AppManager, name, state string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, w := range m.WatcherStatus() {
+			if w.Name == name && w.State == state {
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("watcher %s never became %s: %+v", name, state, m.WatcherStatus())
+}
+
+func TestStopWatchers(t *testing.T) {
+	m := &AppManager{}
+	m.StartWatcher(watchers.Spec{Name: "good", Start: func(stop <-chan struct{}) error {
+		<-stop
+		return nil
+	}})
+	waitState(t, m, "good", "running")
+
+	m.mu.Lock()
+	info := m.watchers[0]
+	m.mu.Unlock()
+	m.stopWatchers()
+
+	select {
+	case <-info.done:
+	default:
+		t.Error("stopWatchers returned before the watcher did")
+	}
+	if len(m.WatcherStatus()) != 0 || len(m.leaked) != 0 {
+		t.Errorf("status %+v, leaked %v", m.WatcherStatus(), m.leaked)
+	}
+}
+
+func TestStuckWatcherIsLeaked(t *testing.T) {
+	defer func(d time.Duration) { stopTimeout = d }(stopTimeout)
+	stopTimeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	m := &AppManager{}
+	m.StartWatcher(watchers.Spec{Name: "stuck", Start: func(stop <-chan struct{}) error {
+		<-release // ignores stop
+		<-stop
+		return nil
+	}})
+	waitState(t, m, "stuck", "running")
+
+	m.stopWatchers()
+	w := m.stillRunning("stuck")
+	if w == nil || w.State != "leaked" || w.Error == "" {
+		t.Fatalf("stillRunning = %+v, want the leaked watcher", w)
+	}
+
+	// A reload keeps it listed instead of starting a second one, and does
+	// not wait for it again
+	m.mu.Lock()
+	m.watchers = append(m.watchers, w)
+	m.mu.Unlock()
+	start := time.Now()
+	m.stopWatchers()
+	if elapsed := time.Since(start); elapsed >= stopTimeout {
+		t.Errorf("second stop waited %v for the leaked watcher", elapsed)
+	}
+
+	close(release)
+	<-w.done
+	if m.stillRunning("stuck") != nil {
+		t.Error("the watcher returned but is still reported as running")
+	}
+	if w.State != "stopped" {
+		t.Errorf("state %q, want stopped", w.State)
+	}
+}
+
+func TestRunWatcherRecoversPanics(t *testing.T) {
+	err := runWatcher(watchers.Spec{Start: func(stop <-chan struct{}) error {
+		panic("boom")
+	}}, nil)
+	if err == nil || err.Error() != "panic: boom" {
+		t.Errorf("err = %v", err)
+	}
+
+	want := errors.New("exit")
+	if err := runWatcher(watchers.Spec{Start: func(stop <-chan struct{}) error { return want }}, nil); err != want {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,137 @@
+package sink
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlushInterval is how long the daemon collects updates before handing them
+// to eww together.
+const FlushInterval = 50 * time.Millisecond
+
+// Eww pushes updates into the running eww daemon. Updates are queued per
+// variable so only the newest value survives a tick, values identical to the
+// last one sent are dropped, and everything pending goes out in a single
+// `eww update A=.. B=..` invocation.
+type Eww struct {
+	mu       sync.Mutex
+	flushMu  sync.Mutex
+	interval time.Duration
+	pending  map[string]string
+	order    []string
+	sent     map[string]string
+	timer    *time.Timer
+	closed   bool
+}
+
+// NewEww returns an eww sink flushing every interval. An interval of zero
+// sends each update right away, which is what short-lived CLI processes need.
+func NewEww(interval time.Duration) *Eww {
+	return &Eww{
+		interval: interval,
+		pending:  make(map[string]string),
+		sent:     make(map[string]string),
+	}
+}
+
+func (e *Eww) Send(u Update) error {
+	value := u.Encode()
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+
+	if _, queued := e.pending[u.Name]; !queued {
+		if last, ok := e.sent[u.Name]; ok && last == value {
+			e.mu.Unlock()
+			return nil
+		}
+		e.order = append(e.order, u.Name)
+	}
+	e.pending[u.Name] = value
+
+	if e.interval <= 0 {
+		e.mu.Unlock()
+		return e.Flush()
+	}
+
+	if e.timer == nil {
+		e.timer = time.AfterFunc(e.interval, func() {
+			if err := e.Flush(); err != nil {
+				log.Printf("eww sink: %v", err)
+			}
+		})
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// Flush sends everything queued right away.
+func (e *Eww) Flush() error {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+
+	e.mu.Lock()
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	if len(e.order) == 0 {
+		e.mu.Unlock()
+		return nil
+	}
+
+	args := []string{"update"}
+	batch := make(map[string]string, len(e.order))
+	for _, name := range e.order {
+		value := e.pending[name]
+		// A value may have been queued and then reverted in the same tick
+		if last, ok := e.sent[name]; ok && last == value {
+			continue
+		}
+		args = append(args, name+"="+value)
+		batch[name] = value
+	}
+	e.pending = make(map[string]string)
+	e.order = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	out, err := exec.Command("eww", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("eww update: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	e.mu.Lock()
+	for name, value := range batch {
+		e.sent[name] = value
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// Reset forgets what was sent, for a freshly started eww that only knows its
+// defvar defaults. The next value of every variable goes out even if it is
+// unchanged.
+func (e *Eww) Reset() {
+	e.mu.Lock()
+	e.sent = make(map[string]string)
+	e.mu.Unlock()
+}
+
+func (e *Eww) Close() error {
+	err := e.Flush()
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	return err
+}
@@ -0,0 +1,140 @@
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEww puts an eww on PATH that logs the arguments of every call, one
+// call per line, and fails while the fail file exists.
+func fakeEww(t *testing.T) (calls func() []string, fail func(bool)) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	failFile := filepath.Join(dir, "fail")
+	script := "#!/bin/sh\n[ -e " + failFile + " ] && { echo no daemon; exit 1; }\necho \"$*\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "eww"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	calls = func() []string {
+		data, _ := os.ReadFile(log)
+		return strings.FieldsFunc(string(data), func(r rune) bool { return r == '\n' })
+	}
+	fail = func(on bool) {
+		if on {
+			os.WriteFile(failFile, nil, 0644)
+		} else {
+			os.Remove(failFile)
+		}
+	}
+	return calls, fail
+}
+
+func TestEwwBatchesAndDedupes(t *testing.T) {
+	calls, _ := fakeEww(t)
+	e := NewEww(time.Hour) // flushed by hand
+
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	e.Send(Update{Name: "B", Value: "x"})
+	e.Send(Update{Name: "A", Value: 2, Plain: true}) // the newest value wins, in first-queued order
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Send(Update{Name: "A", Value: 2, Plain: true}) // unchanged, dropped
+	e.Flush()
+
+	e.Send(Update{Name: "B", Value: "y"})
+	e.Send(Update{Name: "B", Value: "x"}) // reverted within the tick
+	e.Flush()
+
+	e.Reset() // a restarted eww needs everything again
+	e.Send(Update{Name: "A", Value: 2, Plain: true})
+	e.Flush()
+
+	want := []string{`update A=2 B="x"`, "update A=2"}
+	if got := calls(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("eww calls = %q, want %q", got, want)
+	}
+}
+
+func TestEwwFlushesAfterInterval(t *testing.T) {
+	calls, _ := fakeEww(t)
+	e := NewEww(20 * time.Millisecond)
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	e.Send(Update{Name: "B", Value: 2, Plain: true})
+	if got := calls(); len(got) != 0 {
+		t.Fatalf("sent before the interval: %q", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := calls(); len(got) != 1 || got[0] != "update A=1 B=2" {
+		t.Errorf("eww calls = %q, want one batch", got)
+	}
+}
+
+func TestEwwWithoutIntervalSendsRightAway(t *testing.T) {
+	calls, _ := fakeEww(t)
+	e := NewEww(0)
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	e.Send(Update{Name: "A", Value: 2, Plain: true})
+	if got := calls(); strings.Join(got, "|") != "update A=1|update A=2" {
+		t.Errorf("eww calls = %q", got)
+	}
+}
+
+func TestEwwRetriesFailedUpdates(t *testing.T) {
+	calls, fail := fakeEww(t)
+	e := NewEww(time.Hour)
+
+	fail(true)
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	if err := e.Flush(); err == nil || !strings.Contains(err.Error(), "no daemon") {
+		t.Errorf("Flush = %v, want the eww error", err)
+	}
+
+	// Not marked as sent, so the same value goes out once eww is back
+	fail(false)
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	e.Flush()
+	if got := calls(); len(got) != 1 || got[0] != "update A=1" {
+		t.Errorf("eww calls = %q", got)
+	}
+}
+
+func TestEwwClose(t *testing.T) {
+	calls, _ := fakeEww(t)
+	e := NewEww(time.Hour)
+	e.Send(Update{Name: "A", Value: 1, Plain: true})
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	e.Send(Update{Name: "A", Value: 2, Plain: true})
+	e.Flush()
+	if got := calls(); len(got) != 1 || got[0] != "update A=1" {
+		t.Errorf("eww calls = %q, want only the pending update", got)
+	}
+}
+
+func TestStateReplay(t *testing.T) {
+	s := NewState()
+	s.Send(Update{Name: "B", Value: 1})
+	s.Send(Update{Name: "A", Value: 1})
+	s.Send(Update{Name: "B", Value: 2})
+
+	r := NewRecorder()
+	s.Replay(r)
+	got := r.Updates()
+	if len(got) != 2 || got[0].Name != "A" || got[1].Name != "B" || got[1].Value != 2 {
+		t.Errorf("replayed %+v", got)
+	}
+}
@@ -0,0 +1,30 @@
+package sink
+
+import "github.com/hoppxi/wigo/internal/ipc"
+
+// Forward hands updates from CLI processes to the running daemon, which owns
+// the real sinks. When the daemon cannot be reached it falls back to eww.
+type Forward struct {
+	fallback Sink
+}
+
+func NewForward(fallback Sink) *Forward {
+	return &Forward{fallback: fallback}
+}
+
+func (f *Forward) Send(u Update) error {
+	if err := ipc.Call("sink.publish", u, nil); err != nil {
+		if f.fallback == nil {
+			return err
+		}
+		return f.fallback.Send(u)
+	}
+	return nil
+}
+
+func (f *Forward) Close() error {
+	if f.fallback != nil {
+		return f.fallback.Close()
+	}
+	return nil
+}
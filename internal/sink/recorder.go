@@ -0,0 +1,49 @@
+package sink
+
+import "sync"
+
+// Recorder keeps every update in memory, for tests and debugging.
+type Recorder struct {
+	mu      sync.Mutex
+	updates []Update
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Send(u Update) error {
+	r.mu.Lock()
+	r.updates = append(r.updates, u)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Updates returns a copy of everything recorded so far.
+func (r *Recorder) Updates() []Update {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Update(nil), r.updates...)
+}
+
+// Last returns the most recent update for name.
+func (r *Recorder) Last(name string) (Update, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.updates) - 1; i >= 0; i-- {
+		if r.updates[i].Name == name {
+			return r.updates[i], true
+		}
+	}
+	return Update{}, false
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.updates = nil
+	r.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Update is a new value for one frontend variable.
+type Update struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+	// Plain values are formatted with %v instead of being JSON encoded, the
+	// way eww expects booleans, numbers and bare strings.
+	Plain bool `json:"plain,omitempty"`
+}
+
+// Encode returns the value the way it is handed to eww.
+func (u Update) Encode() string {
+	if u.Plain {
+		return fmt.Sprintf("%v", u.Value)
+	}
+	data, _ := json.Marshal(u.Value)
+	return string(data)
+}
+
+// Sink receives every variable update published by the watchers.
+type Sink interface {
+	Send(u Update) error
+	Close() error
+}
+
+// Multi fans updates out to several sinks.
+type Multi struct {
+	mu    sync.RWMutex
+	sinks []Sink
+}
+
+func NewMulti(sinks ...Sink) *Multi {
+	return &Multi{sinks: sinks}
+}
+
+func (m *Multi) Add(s Sink) {
+	m.mu.Lock()
+	m.sinks = append(m.sinks, s)
+	m.mu.Unlock()
+}
+
+// Set replaces the sinks, closing the previous ones.
+func (m *Multi) Set(sinks ...Sink) {
+	m.mu.Lock()
+	old := m.sinks
+	m.sinks = sinks
+	m.mu.Unlock()
+
+	for _, s := range old {
+		s.Close()
+	}
+}
+
+func (m *Multi) Send(u Update) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Send(u); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Multi) Close() error {
+	m.mu.Lock()
+	sinks := m.sinks
+	m.sinks = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Default is what the watchers publish through. Outside the daemon it talks
+// to eww directly.
+var Default = NewMulti(NewEww(0))
+
+// Publish sends value JSON encoded.
+func Publish(name string, value any) error {
+	return Default.Send(Update{Name: name, Value: value})
+}
+
+// PublishPlain sends value formatted with %v.
+func PublishPlain(name string, value any) error {
+	return Default.Send(Update{Name: name, Value: value, Plain: true})
+}
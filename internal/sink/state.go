@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// State remembers the last update of every variable, so it can be queried
+// over IPC and replayed to a frontend that restarted or reconnected.
+type State struct {
+	mu   sync.RWMutex
+	last map[string]Update
+}
+
+func NewState() *State {
+	return &State{last: make(map[string]Update)}
+}
+
+func (s *State) Send(u Update) error {
+	s.mu.Lock()
+	s.last[u.Name] = u
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *State) Close() error {
+	return nil
+}
+
+// Get returns the last update of name.
+func (s *State) Get(name string) (Update, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.last[name]
+	return u, ok
+}
+
+// Updates returns the last update of every variable, sorted by name.
+func (s *State) Updates() []Update {
+	s.mu.RLock()
+	out := make([]Update, 0, len(s.last))
+	for _, u := range s.last {
+		out = append(out, u)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(out, func(a, b Update) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return out
+}
+
+// Replay sends the last update of every variable to dst.
+func (s *State) Replay(dst Sink) error {
+	var errs []error
+	for _, u := range s.Updates() {
+		if err := dst.Send(u); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,33 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer prints every update as a JSON line, which makes it possible to run
+// wigo without any frontend at all.
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+func NewStdout() *Writer {
+	return NewWriter(os.Stdout)
+}
+
+func (w *Writer) Send(u Update) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(u)
+}
+
+func (w *Writer) Close() error {
+	return nil
+}
@@ -0,0 +1,21 @@
+package sink
+
+import "github.com/hoppxi/wigo/internal/bus"
+
+// Stream publishes updates to IPC subscribers through the event hub.
+type Stream struct {
+	hub *bus.Hub
+}
+
+func NewStream(hub *bus.Hub) *Stream {
+	return &Stream{hub: hub}
+}
+
+func (s *Stream) Send(u Update) error {
+	s.hub.Publish(u.Name, u.Value)
+	return nil
+}
+
+func (s *Stream) Close() error {
+	return nil
+}
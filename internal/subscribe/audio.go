@@ -11,7 +11,8 @@ type AudioEvent struct {
 	Index    uint32
 }
 
-func AudioEvents() <-chan AudioEvent {
+// AudioEvents follows sink changes of the pulse server until stop fires.
+func AudioEvents(stop <-chan struct{}) <-chan AudioEvent {
 	out := make(chan AudioEvent, 16)
 
 	client, conn, err := proto.Connect("")
@@ -21,7 +22,7 @@ func AudioEvents() <-chan AudioEvent {
 		return out
 	}
 	go func() {
-		defer conn.Close()
+		defer closeWhen(stop, conn)()
 		ch := make(chan struct{}, 1)
 
 		client.Callback = func(val any) {
@@ -60,7 +61,12 @@ func AudioEvents() <-chan AudioEvent {
 		}
 		defaultSinkName = serverInfo.DefaultSinkName
 
-		for range ch {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ch:
+			}
 			repl := proto.GetSinkInfoReply{}
 			err = client.Request(&proto.GetSinkInfo{SinkIndex: proto.Undefined, SinkName: defaultSinkName}, &repl)
 			if err != nil {
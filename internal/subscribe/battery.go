@@ -16,7 +16,8 @@ type BatteryEventsT struct {
 	DynamicChange    <-chan struct{}
 }
 
-func BatteryEvents() BatteryEventsT {
+// BatteryEvents follows power supply uevents until stop fires.
+func BatteryEvents(stop <-chan struct{}) BatteryEventsT {
 	low20 := make(chan struct{}, 1)
 	low5 := make(chan struct{}, 1)
 	full := make(chan struct{}, 1)
@@ -25,37 +26,25 @@ func BatteryEvents() BatteryEventsT {
 	dynamic := make(chan struct{}, 1)
 
 	go func() {
-		fd, err := syscall.Socket(
-			syscall.AF_NETLINK,
-			syscall.SOCK_RAW,
-			syscall.NETLINK_KOBJECT_UEVENT,
-		)
+		fd, err := openUevents()
 		if err != nil {
 			log.Printf("battery: socket error: %v", err)
 			return
 		}
 		defer syscall.Close(fd)
 
-		addr := &syscall.SockaddrNetlink{
-			Family: syscall.AF_NETLINK,
-			Groups: 1,
-		}
-		if err := syscall.Bind(fd, addr); err != nil {
-			log.Printf("battery: bind error: %v", err)
-			return
-		}
-
 		buf := make([]byte, 4096)
 
 		for {
-			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			msg, err := readUevent(fd, buf, stop)
+			if err == errStopped {
+				return
+			}
 			if err != nil {
 				log.Printf("battery: recv error: %v", err)
 				continue
 			}
 
-			msg := string(buf[:n])
-
 			if !strings.Contains(msg, "SUBSYSTEM=power_supply") {
 				continue
 			}
@@ -17,7 +17,8 @@ type LEDsEventChannels struct {
 	ScrollOff chan struct{}
 }
 
-func LEDsEvents() *LEDsEventChannels {
+// LEDsEvents polls the lock LEDs until stop fires.
+func LEDsEvents(stop <-chan struct{}) *LEDsEventChannels {
 	ev := &LEDsEventChannels{
 		CapsOn:    make(chan struct{}),
 		CapsOff:   make(chan struct{}),
@@ -27,7 +28,7 @@ func LEDsEvents() *LEDsEventChannels {
 		ScrollOff: make(chan struct{}),
 	}
 
-	go watchLockLEDs(ev)
+	go watchLockLEDs(ev, stop)
 	return ev
 }
 
@@ -68,7 +69,7 @@ func readBrightness(path string) (bool, error) {
 	return v == 1, nil
 }
 
-func watchLockLEDs(ev *LEDsEventChannels) {
+func watchLockLEDs(ev *LEDsEventChannels, stop <-chan struct{}) {
 	leds := discoverLockLEDs()
 	prev := make(map[string]bool)
 
@@ -78,8 +79,23 @@ func watchLockLEDs(ev *LEDsEventChannels) {
 		}
 	}
 
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	send := func(ch chan struct{}) bool {
+		select {
+		case ch <- struct{}{}:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
 	for {
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
 
 		for k, p := range leds {
 			cur, err := readBrightness(p)
@@ -88,26 +104,30 @@ func watchLockLEDs(ev *LEDsEventChannels) {
 			}
 
 			if cur != prev[k] {
+				ok := true
 				switch k {
 				case "caps":
 					if cur {
-						ev.CapsOn <- struct{}{}
+						ok = send(ev.CapsOn)
 					} else {
-						ev.CapsOff <- struct{}{}
+						ok = send(ev.CapsOff)
 					}
 				case "num":
 					if cur {
-						ev.NumOn <- struct{}{}
+						ok = send(ev.NumOn)
 					} else {
-						ev.NumOff <- struct{}{}
+						ok = send(ev.NumOff)
 					}
 				case "scroll":
 					if cur {
-						ev.ScrollOn <- struct{}{}
+						ok = send(ev.ScrollOn)
 					} else {
-						ev.ScrollOff <- struct{}{}
+						ok = send(ev.ScrollOff)
 					}
 				}
+				if !ok {
+					return
+				}
 				prev[k] = cur
 			}
 		}
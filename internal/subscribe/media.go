@@ -7,8 +7,8 @@ import (
 	"github.com/godbus/dbus/v5"
 )
 
-// any MPRIS player
-func MediaEvents() <-chan MediaEvent {
+// MediaEvents follows any MPRIS player until stop fires.
+func MediaEvents(stop <-chan struct{}) <-chan MediaEvent {
 	out := make(chan MediaEvent, 20)
 
 	go func() {
@@ -18,6 +18,16 @@ func MediaEvents() <-chan MediaEvent {
 			close(out)
 			return
 		}
+		defer closeWhen(stop, conn)()
+
+		send := func(e MediaEvent) bool {
+			select {
+			case out <- e:
+				return true
+			case <-stop:
+				return false
+			}
+		}
 
 		signalChan := make(chan *dbus.Signal, 50)
 		conn.Signal(signalChan)
@@ -63,30 +73,36 @@ func MediaEvents() <-chan MediaEvent {
 
 				if props, ok := sig.Body[1].(map[string]dbus.Variant); ok {
 					for prop, val := range props {
-						out <- MediaEvent{
+						if !send(MediaEvent{
 							Player:   player,
 							Property: prop,
 							Value:    val.Value(),
+						}) {
+							return
 						}
 					}
 				}
 
 				if invalidated, ok := sig.Body[2].([]string); ok {
 					for _, prop := range invalidated {
-						out <- MediaEvent{
+						if !send(MediaEvent{
 							Player:   player,
 							Property: prop,
 							Value:    nil, // property invalidated
+						}) {
+							return
 						}
 					}
 				}
 
 			case "org.mpris.MediaPlayer2.Player.Seeked":
 
-				out <- MediaEvent{
+				if !send(MediaEvent{
 					Player:   player,
 					Property: "Position", // Trigger property
 					Value:    nil,
+				}) {
+					return
 				}
 			}
 		}
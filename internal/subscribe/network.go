@@ -7,15 +7,18 @@ import (
 	"github.com/godbus/dbus/v5"
 )
 
-func NetworkEvents() <-chan NetworkEvent {
+// NetworkEvents follows the system bus until stop fires.
+func NetworkEvents(stop <-chan struct{}) <-chan NetworkEvent {
 	events := make(chan NetworkEvent, 10)
 
 	go func() {
-		conn, err := dbus.SystemBus()
+		// A private connection, which can be closed without affecting others
+		conn, err := dbus.ConnectSystemBus()
 		if err != nil {
 			log.Printf("NetworkEvents: failed to connect to system bus: %v", err)
 			return
 		}
+		defer closeWhen(stop, conn)()
 
 		signals := make(chan *dbus.Signal, 32)
 		conn.Signal(signals)
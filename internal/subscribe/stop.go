@@ -0,0 +1,71 @@
+package subscribe
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+)
+
+// closeWhen closes c once stop fires, which ends the reads a source is
+// blocked in. Calling the returned func closes c right away, for a source
+// that ended by itself.
+func closeWhen(stop <-chan struct{}, c io.Closer) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		c.Close()
+	}()
+	return func() { close(done) }
+}
+
+// ueventPoll is how often a uevent reader checks for stop, since closing a
+// netlink socket does not wake a blocked recvfrom.
+const ueventPoll = 500 * time.Millisecond
+
+// openUevents opens a netlink socket receiving the kernel's uevents.
+func openUevents() (int, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return -1, err
+	}
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: 1, // listen to broadcast uevents
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	tv := syscall.NsecToTimeval(ueventPoll.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// errStopped is returned by readUevent once stop fired.
+var errStopped = errors.New("stopped")
+
+// readUevent returns the next uevent of fd.
+func readUevent(fd int, buf []byte, stop <-chan struct{}) (string, error) {
+	for {
+		select {
+		case <-stop:
+			return "", errStopped
+		default:
+		}
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+}
@@ -16,6 +16,24 @@ type Notification struct {
 	Timeout       int32                   `json:"timeout"`
 	ExpireTimeout int32                   `json:"expire_timeout"`
 	Timestamp     int64                   `json:"timestamp"`
+
+	// Parsed from the spec hints of the same name
+	Resident      bool   `json:"resident,omitempty"`
+	Transient     bool   `json:"transient,omitempty"`
+	ActionIcons   bool   `json:"action_icons,omitempty"`
+	SoundFile     string `json:"sound_file,omitempty"`
+	SoundName     string `json:"sound_name,omitempty"`
+	SuppressSound bool   `json:"suppress_sound,omitempty"`
+
+	// Reply is set when the app takes inline replies
+	Reply *ReplyAction `json:"reply,omitempty"`
+}
+
+// ReplyAction describes the text entry of an inline-reply action.
+type ReplyAction struct {
+	Label       string `json:"label"`
+	Placeholder string `json:"placeholder,omitempty"`
+	Submit      string `json:"submit,omitempty"`
 }
 
 type NetworkEvent struct{}
@@ -26,7 +26,8 @@ type Event struct {
 	Raw     string
 }
 
-func SubscribeEvents() (<-chan Event, error) {
+// SubscribeEvents reads Hyprland's event socket until stop fires.
+func SubscribeEvents(stop <-chan struct{}) (<-chan Event, error) {
 	path := eventSocket()
 	conn, err := net.Dial("unix", path)
 	if err != nil {
@@ -37,16 +38,16 @@ func SubscribeEvents() (<-chan Event, error) {
 
 	go func() {
 		defer close(out)
-		defer conn.Close()
+		defer closeWhen(stop, conn)()
 
 		sc := bufio.NewScanner(conn)
 		for sc.Scan() {
 			line := sc.Text()
 			name, payload := SplitEvent(line)
-			out <- Event{
-				Name:    name,
-				Payload: payload,
-				Raw:     line,
+			select {
+			case out <- Event{Name: name, Payload: payload, Raw: line}:
+			case <-stop:
+				return
 			}
 		}
 	}()
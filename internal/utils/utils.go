@@ -382,3 +382,36 @@ func buildFilters(inputs []string) [][]interface{} {
 
 	return filters
 }
+
+// WriteFileAtomic writes data to a temporary file of its own next to name,
+// syncs it and renames it over name, so readers and concurrent writers never
+// see a partial file. The file is left readable by everyone, like
+// os.WriteFile with 0644.
+func WriteFileAtomic(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "sub", "state.json")
+
+	if err := WriteFileAtomic(name, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(name, []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(name)
+	if string(data) != "two" {
+		t.Errorf("content %q", data)
+	}
+	if info, _ := os.Stat(name); info.Mode().Perm() != 0644 {
+		t.Errorf("mode %v, want 0644", info.Mode().Perm())
+	}
+
+	// Concurrent writers each go through a file of their own
+	var wg sync.WaitGroup
+	contents := [][]byte{bytes.Repeat([]byte("a"), 1<<16), bytes.Repeat([]byte("b"), 1<<16)}
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := WriteFileAtomic(name, contents[i%2]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	data, _ = os.ReadFile(name)
+	if !bytes.Equal(data, contents[0]) && !bytes.Equal(data, contents[1]) {
+		t.Errorf("mixed content of %d bytes", len(data))
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	if len(entries) != 1 {
+		t.Errorf("left temporary files behind: %v", entries)
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"log"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/hoppxi/wigo/internal/sink"
 	"github.com/hoppxi/wigo/internal/utils"
 	"github.com/ncruces/zenity"
 	"github.com/spf13/viper"
@@ -52,7 +52,7 @@ func SetWallpaperStartup() {
 	}
 
 	if rounded != "" {
-		if err := exec.Command("eww", "update", fmt.Sprintf("%s=%s", WALLPAPER, rounded)).Run(); err != nil {
+		if err := sink.PublishPlain(WALLPAPER, rounded); err != nil {
 			log.Printf("Failed to apply wallpaper: %v", err)
 		}
 	}
@@ -81,11 +81,8 @@ func SetWallpaper(wallpaperPath string) error {
 		return err
 	}
 
-	ewwCommand := fmt.Sprintf("%s=%s", WALLPAPER, rounded)
-
-	cmd := exec.Command("eww", "update", ewwCommand)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run 'eww update %s': %w", ewwCommand, err)
+	if err := sink.PublishPlain(WALLPAPER, rounded); err != nil {
+		return fmt.Errorf("failed to publish %s=%s: %w", WALLPAPER, rounded, err)
 	}
 	log.Printf("Successfully updated Eww variable %s.", rounded)
 
@@ -1,9 +1,7 @@
 package watchers
 
 import (
-	"encoding/json"
-	"fmt"
-	"os/exec"
+	"errors"
 	"time"
 
 	"github.com/hoppxi/wigo/internal/subscribe"
@@ -11,47 +9,40 @@ import (
 	"github.com/hoppxi/wigo/pkg/iconsinfo"
 )
 
-func StartAudioWatcher(stop <-chan struct{}) {
+func StartAudioWatcher(stop <-chan struct{}) error {
 	prev, _ := audioinfo.GetAudioInfo()
-	updateEww("AUDIO_INFO", prev)
+	publish("AUDIO_INFO", prev)
 
-	iconInfo := iconsinfo.GetIcons()
-	updateEww("ICONS_INFO", iconInfo)
+	updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithAudio(prev) })
 
-	events := subscribe.AudioEvents()
+	events := subscribe.AudioEvents(stop)
+	osdTimeout := OptionsFor("audio").osdTimeout(3 * time.Second)
 	var osdTimer *time.Timer
 
 	for {
 		select {
 		case <-stop:
-			return
-		case <-events:
+			return nil
+		case _, ok := <-events:
+			if !ok {
+				return errors.New("pulseaudio event stream closed")
+			}
 			info, _ := audioinfo.GetAudioInfo()
-			updateEww("AUDIO_INFO", info)
+			publish("AUDIO_INFO", info)
 
-			iconInfo := iconsinfo.GetIcons()
-			updateEww("ICONS_INFO", iconInfo)
+			updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithAudio(info) })
 
 			if prev.Output.Level != info.Output.Level {
-				updateEwwNoJson("OSD_VOLUME", true)
+				publishPlain("OSD_VOLUME", true)
 				if osdTimer != nil {
 					osdTimer.Stop()
 				}
 
-				osdTimer = time.AfterFunc(3*time.Second, func() {
-					updateEwwNoJson("OSD_VOLUME", false)
+				osdTimer = time.AfterFunc(osdTimeout, func() {
+					publishPlain("OSD_VOLUME", false)
 				})
 				prev = info
 			}
 		}
 	}
 }
-
-func updateEww(module string, data any) {
-	jsonData, _ := json.Marshal(data)
-	exec.Command("eww", "update", module+"="+string(jsonData)).Run()
-}
-
-func updateEwwNoJson(module string, data any) {
-	exec.Command("eww", "update", fmt.Sprintf("%s=%v", module, data)).Run()
-}
@@ -8,48 +8,57 @@ import (
 	"github.com/hoppxi/wigo/pkg/batteryinfo"
 )
 
-func StartBatteryWatcher(stop <-chan struct{}) {
+func StartBatteryWatcher(stop <-chan struct{}) error {
 
 	info, err := batteryinfo.GetBatteryInfo()
 	if err != nil {
 		fmt.Println(err)
 	}
-	updateEww("BATTERY_INFO", info)
+	publish("BATTERY_INFO", info)
 
-	events := subscribe.BatteryEvents()
+	events := subscribe.BatteryEvents(stop)
+	osdTimeout := OptionsFor("battery").osdTimeout(5 * time.Second)
+
+	// One timer per OSD, so one popping up does not hold the others back
+	osdTimers := make(map[string]*time.Timer)
+	defer func() {
+		for name, t := range osdTimers {
+			if t.Stop() {
+				publishPlain(name, false)
+			}
+		}
+	}()
+	showOSD := func(name string) {
+		publishPlain(name, true)
+		if t, ok := osdTimers[name]; ok {
+			t.Stop()
+		}
+		osdTimers[name] = time.AfterFunc(osdTimeout, func() {
+			publishPlain(name, false)
+		})
+	}
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events.BatteryFull:
-			updateEwwNoJson("OSD_BATTERY_FULL", true)
-			time.Sleep(5 * time.Second)
-			updateEwwNoJson("OSD_BATTERY_FULL", false)
-
+			showOSD("OSD_BATTERY_FULL")
 		case <-events.BatteryLow20:
-			updateEwwNoJson("OSD_BATTERY_LOW_20", true)
-			time.Sleep(5 * time.Second)
-			updateEwwNoJson("OSD_BATTERY_LOW_20", false)
+			showOSD("OSD_BATTERY_LOW_20")
 		case <-events.BatteryLow5:
-			updateEwwNoJson("OSD_BATTERY_LOW_5", true)
-			time.Sleep(5 * time.Second)
-			updateEwwNoJson("OSD_BATTERY_LOW_5", false)
+			showOSD("OSD_BATTERY_LOW_5")
 		case <-events.ChargerPlugged:
-			updateEwwNoJson("OSD_CHARGER_PLUGGED", true)
-			time.Sleep(5 * time.Second)
-			updateEwwNoJson("OSD_CHARGER_PLUGGED", false)
+			showOSD("OSD_CHARGER_PLUGGED")
 		case <-events.ChargerUnplugged:
-			updateEwwNoJson("OSD_CHARGER_UNPLUGGED", true)
-			time.Sleep(5 * time.Second)
-			updateEwwNoJson("OSD_CHARGER_UNPLUGGED", false)
+			showOSD("OSD_CHARGER_UNPLUGGED")
 		case <-events.DynamicChange:
 			dynamicInfo, err := batteryinfo.GetBatteryDynamicInfo()
 			if err != nil {
 				fmt.Println(err)
 			}
 
-			updateEww("BATTERY_DYNAMIC_INFO", dynamicInfo)
+			publish("BATTERY_DYNAMIC_INFO", dynamicInfo)
 		}
 	}
 }
@@ -6,25 +6,23 @@ import (
 	"github.com/hoppxi/wigo/pkg/iconsinfo"
 )
 
-func StartBluetoothWatcher(stop <-chan struct{}) {
+func StartBluetoothWatcher(stop <-chan struct{}) error {
 	info, _ := btinfo.GetBluetoothInfo()
-	updateEww("BLUETOOTH_INFO", info)
+	publish("BLUETOOTH_INFO", info)
 
-	iconsInfo := iconsinfo.GetIcons()
-	updateEww("ICONS_INFO", iconsInfo)
+	updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithBluetooth(info) })
 
-	events := subscribe.BluetoothEvents()
+	events := subscribe.BluetoothEvents(stop)
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events:
 			info, _ := btinfo.GetBluetoothInfo()
-			updateEww("BLUETOOTH_INFO", info)
+			publish("BLUETOOTH_INFO", info)
 
-			iconsInfo := iconsinfo.GetIcons()
-			updateEww("ICONS_INFO", iconsInfo)
+			updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithBluetooth(info) })
 		}
 	}
 }
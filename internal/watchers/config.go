@@ -11,7 +11,7 @@ import (
 )
 
 func ConfigUpdate(v *viper.Viper) {
-	updateEww("APPS_CONFIG", v.Get("apps"))
+	publish("APPS_CONFIG", v.Get("apps"))
 
 	general, ok := v.Get("general").(map[string]any)
 	if !ok {
@@ -39,5 +39,5 @@ func ConfigUpdate(v *viper.Viper) {
 
 	general["profile_pic"] = rounded
 
-	updateEww("GENERAL_CONFIG", general)
+	publish("GENERAL_CONFIG", general)
 }
@@ -7,30 +7,31 @@ import (
 	"github.com/hoppxi/wigo/pkg/displayinfo"
 )
 
-func StartDisplayWatcher(stop <-chan struct{}) {
+func StartDisplayWatcher(stop <-chan struct{}) error {
 	prev, _ := displayinfo.GetDisplayInfo()
-	updateEww("DISPLAY_INFO", prev)
+	publish("DISPLAY_INFO", prev)
 
-	events := subscribe.DisplayEvents()
+	events := subscribe.DisplayEvents(stop)
+	osdTimeout := OptionsFor("display").osdTimeout(3 * time.Second)
 	var osdTimer *time.Timer
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events:
 			info, _ := displayinfo.GetDisplayInfo()
-			updateEww("DISPLAY_INFO", info)
+			publish("DISPLAY_INFO", info)
 
 			if prev.Level != info.Level {
 
-				updateEwwNoJson("OSD_DISPLAY", true)
+				publishPlain("OSD_DISPLAY", true)
 				if osdTimer != nil {
 					osdTimer.Stop()
 				}
 
-				osdTimer = time.AfterFunc(3*time.Second, func() {
-					updateEwwNoJson("OSD_DISPLAY", false)
+				osdTimer = time.AfterFunc(osdTimeout, func() {
+					publishPlain("OSD_DISPLAY", false)
 				})
 
 				prev = info
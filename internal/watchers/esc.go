@@ -1,6 +1,7 @@
 package watchers
 
 import (
+	"errors"
 	"log"
 	"os"
 
@@ -10,10 +11,10 @@ import (
 
 const stateFile = "/tmp/wigo/wigo_widget_state"
 
-func StartEscWatcher(stop <-chan struct{}) {
+func StartEscWatcher(stop <-chan struct{}) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer watcher.Close()
 
@@ -46,24 +47,27 @@ func StartEscWatcher(stop <-chan struct{}) {
 	checkState()
 
 	dir := "/tmp/wigo"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
 	if err := watcher.Add(dir); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case event, ok := <-watcher.Events:
 			if !ok {
-				return
+				return errors.New("fsnotify event channel closed")
 			}
 			if event.Name == stateFile && (event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Remove == fsnotify.Remove) {
 				checkState()
 			}
 		case err, ok := <-watcher.Errors:
 			if !ok {
-				return
+				return errors.New("fsnotify error channel closed")
 			}
 			log.Println("watcher error:", err)
 		}
@@ -0,0 +1,28 @@
+package watchers
+
+import (
+	"sync"
+
+	"github.com/hoppxi/wigo/pkg/iconsinfo"
+)
+
+// ICONS_INFO combines audio, network and bluetooth state. Each watcher only
+// refreshes its own part instead of querying all three services again.
+var icons = struct {
+	mu     sync.Mutex
+	info   iconsinfo.IconsInfo
+	loaded bool
+}{}
+
+func updateIcons(apply func(iconsinfo.IconsInfo) iconsinfo.IconsInfo) {
+	icons.mu.Lock()
+	if !icons.loaded {
+		icons.info = iconsinfo.GetIcons()
+		icons.loaded = true
+	}
+	icons.info = apply(icons.info)
+	info := icons.info
+	icons.mu.Unlock()
+
+	publish("ICONS_INFO", info)
+}
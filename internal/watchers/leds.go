@@ -4,25 +4,25 @@ import (
 	"github.com/hoppxi/wigo/internal/subscribe"
 )
 
-func StartLEDsWatcher(stop <-chan struct{}) {
-	events := subscribe.LEDsEvents()
+func StartLEDsWatcher(stop <-chan struct{}) error {
+	events := subscribe.LEDsEvents(stop)
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events.CapsOff:
-			updateEwwNoJson("OSD_CAPS", false)
+			publishPlain("OSD_CAPS", false)
 		case <-events.CapsOn:
-			updateEwwNoJson("OSD_CAPS", true)
+			publishPlain("OSD_CAPS", true)
 		case <-events.NumOff:
-			updateEwwNoJson("OSD_NUM", false)
+			publishPlain("OSD_NUM", false)
 		case <-events.NumOn:
-			updateEwwNoJson("OSD_NUM", true)
+			publishPlain("OSD_NUM", true)
 		case <-events.ScrollOff:
-			updateEwwNoJson("OSD_SCROLL", false)
+			publishPlain("OSD_SCROLL", false)
 		case <-events.ScrollOn:
-			updateEwwNoJson("OSD_SCROLL", true)
+			publishPlain("OSD_SCROLL", true)
 		}
 	}
 }
@@ -7,10 +7,11 @@ import (
 	"github.com/hoppxi/wigo/pkg/mediainfo"
 )
 
-func StartMediaWatcher(stop <-chan struct{}) {
-	events := subscribe.MediaEvents()
+func StartMediaWatcher(stop <-chan struct{}) error {
+	events := subscribe.MediaEvents(stop)
 
-	ticker := time.NewTicker(1 * time.Second)
+	tick := OptionsFor("media").interval(time.Second)
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
 
 	var currentPos int64 = 0
@@ -24,7 +25,7 @@ func StartMediaWatcher(stop <-chan struct{}) {
 		totalLen = info.LengthRaw
 		isPlaying = info.Playing
 
-		updateEww("MEDIA_INFO", info)
+		publish("MEDIA_INFO", info)
 		updateTime(currentPos, totalLen)
 	}
 
@@ -33,12 +34,12 @@ func StartMediaWatcher(stop <-chan struct{}) {
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events:
 			syncState()
 		case <-ticker.C:
 			if isPlaying {
-				currentPos += 1_000_000
+				currentPos += tick.Microseconds()
 				if currentPos > totalLen {
 					currentPos = totalLen
 				}
@@ -51,6 +52,6 @@ func StartMediaWatcher(stop <-chan struct{}) {
 func updateTime(pos, length int64) {
 	elapsedStr := mediainfo.FormatDurationMicros(pos)
 	normFloat := mediainfo.FormatNormalized(pos, length)
-	updateEwwNoJson("MEDIA_ELAPSED_TIME", elapsedStr)
-	updateEwwNoJson("MEDIA_NORMALIZED_ELAPSED_TIME", normFloat)
+	publishPlain("MEDIA_ELAPSED_TIME", elapsedStr)
+	publishPlain("MEDIA_NORMALIZED_ELAPSED_TIME", normFloat)
 }
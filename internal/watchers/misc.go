@@ -6,19 +6,19 @@ import (
 	"github.com/hoppxi/wigo/pkg/miscinfo"
 )
 
-func StartMiscWatcher(stop <-chan struct{}) {
+func StartMiscWatcher(stop <-chan struct{}) error {
 	info := miscinfo.GetMisc()
-	updateEww("MISC_INFO", info)
-	ticker := time.NewTicker(time.Minute)
+	publish("MISC_INFO", info)
+	ticker := time.NewTicker(OptionsFor("misc").interval(time.Minute))
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-ticker.C:
 			info := miscinfo.GetMisc()
-			updateEww("MISC_INFO", info)
+			publish("MISC_INFO", info)
 		}
 	}
 }
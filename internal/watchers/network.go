@@ -6,25 +6,23 @@ import (
 	"github.com/hoppxi/wigo/pkg/netinfo"
 )
 
-func StartNetworkWatcher(stop <-chan struct{}) {
+func StartNetworkWatcher(stop <-chan struct{}) error {
 	info, _ := netinfo.GetNetworkInfo()
-	updateEww("NETWORK_INFO", info)
+	publish("NETWORK_INFO", info)
 
-	iconsInfo := iconsinfo.GetIcons()
-	updateEww("ICONS_INFO", iconsInfo)
+	updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithNetwork(info) })
 
-	events := subscribe.NetworkEvents()
+	events := subscribe.NetworkEvents(stop)
 
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case <-events:
 			info, _ := netinfo.GetNetworkInfo()
-			updateEww("NETWORK_INFO", info)
+			publish("NETWORK_INFO", info)
 
-			iconsInfo := iconsinfo.GetIcons()
-			updateEww("ICONS_INFO", iconsInfo)
+			updateIcons(func(i iconsinfo.IconsInfo) iconsinfo.IconsInfo { return i.WithNetwork(info) })
 		}
 	}
 }
@@ -1,13 +1,9 @@
 package watchers
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"log"
 	"os"
 	"os/exec"
@@ -18,6 +14,7 @@ import (
 	"time"
 
 	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/bus"
 	"github.com/hoppxi/wigo/internal/subscribe"
 )
 
@@ -27,13 +24,32 @@ var (
 	cacheDir       = filepath.Join(os.Getenv("HOME"), ".cache/wigo/images")
 	execArgsGlobal []string
 
-	// Global DBus connection to ensure signals are emitted from the owner
-	dbusConn *dbus.Conn
+	// Global DBus connection to ensure signals are emitted from the owner.
+	// Expiry timers and D-Bus handlers read it while the watcher may be
+	// restarting, hence atomic.
+	dbusConn atomic.Pointer[dbus.Conn]
 
 	activeNotifications = struct {
 		mu   sync.Mutex
 		data map[uint32]subscribe.Notification
 	}{data: make(map[uint32]subscribe.Notification)}
+
+	// expiry holds the timer closing each notification that has a timeout
+	expiry = struct {
+		mu     sync.Mutex
+		timers map[uint32]*time.Timer
+	}{timers: make(map[uint32]*time.Timer)}
+
+	lastNotificationID atomic.Uint32
+	seedNotificationID sync.Once
+)
+
+// Reasons for NotificationClosed, from the spec.
+const (
+	closeExpired   uint32 = 1
+	closeDismissed uint32 = 2
+	closeByCall    uint32 = 3
+	closeUndefined uint32 = 4
 )
 
 // Ensure cache directory exists
@@ -45,142 +61,72 @@ type notificationHelper struct{}
 
 var NotificationHelper = &notificationHelper{}
 
-func (h *notificationHelper) SetDNDState(state string) error {
-	dir := filepath.Dir(dndPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Println("failed to create DND directory:", err)
+func (h *notificationHelper) ClearHistory() error {
+	if err := notificationHistory.Clear(); err != nil {
 		return err
 	}
-
-	switch state {
-	case "on":
-		return os.WriteFile(dndPath, []byte("on"), 0644)
-	case "off":
-		return os.WriteFile(dndPath, []byte("off"), 0644)
-	default:
-		return errors.New("use --dnd on|off")
-	}
+	publishHistory([]subscribe.Notification{})
+	return nil
 }
 
-func (h *notificationHelper) IsDND() bool {
-	data, err := os.ReadFile(dndPath)
-	return err == nil && string(data) == "on"
+func (h *notificationHelper) GetHistoryCount() int {
+	return notificationHistory.Count()
 }
 
-func (h *notificationHelper) GetDNDState() bool {
-	return h.IsDND()
-}
+// CloseNotificationByID closes it, updates history, and updates UI
+func (h *notificationHelper) CloseNotificationByID(id uint32) {
+	closeActive(id, closeDismissed)
 
-func (h *notificationHelper) ClearHistory() error {
-	if err := os.Remove(historyPath); err != nil && !os.IsNotExist(err) {
-		return err
+	// Remove from persistence/history
+	if notificationHistory.Remove(id) {
+		publishHistory(notificationHistory.List())
 	}
-	runEWWUpdate("NOTIFICATION_HISTORY", []subscribe.Notification{})
-	return nil
 }
 
-func (h *notificationHelper) ToggleDND() bool {
-	path := dndPath
-	state := false
-	data, err := os.ReadFile(path)
-	if err == nil && string(data) == "on" {
-		state = true
-	}
-
-	newState := "off"
-	if !state {
-		newState = "on"
+// CloseGroup removes every notification of group key from the active view,
+// keeping them in history.
+func (h *notificationHelper) CloseGroup(key string) int {
+	activeNotifications.mu.Lock()
+	ids := groupIDs(getSortedSnapshot(), key)
+	for _, id := range ids {
+		delete(activeNotifications.data, id)
+		cancelExpiry(id)
 	}
+	snapshot := getSortedSnapshot()
+	activeNotifications.mu.Unlock()
 
-	dir := filepath.Dir(path)
-	os.MkdirAll(dir, 0755)
-	os.WriteFile(path, []byte(newState), 0644)
-
-	return newState == "on"
-}
-
-func (h *notificationHelper) GetHistoryCount() int {
-	data, err := os.ReadFile(historyPath)
-	if err != nil {
-		return 0
-	}
-	lines := bytes.Split(data, []byte("\n"))
-	count := 0
-	for _, l := range lines {
-		if len(l) > 0 {
-			count++
-		}
+	publishActive(snapshot)
+	for _, id := range ids {
+		emitClosed(id, closeDismissed)
 	}
-	return count
+	return len(ids)
 }
 
-// CloseNotificationByID closes it, updates history, and updates UI
-func (h *notificationHelper) CloseNotificationByID(id uint32) {
-	activeNotifications.mu.Lock()
-	delete(activeNotifications.data, id)
-	snapshot := getSortedSnapshot() // Use sorted snapshot
-	activeNotifications.mu.Unlock()
-
-	runEWWUpdate("NOTIFICATION", snapshot)
+// DismissGroup closes every notification of group key and removes the group
+// from history.
+func (h *notificationHelper) DismissGroup(key string) int {
+	closed := h.CloseGroup(key)
 
-	// Remove from persistence/history
-	history := loadHistory()
-	newHistory := make([]subscribe.Notification, 0)
-	for _, n := range history {
-		if n.ID != id {
-			newHistory = append(newHistory, n)
-		}
+	removed := notificationHistory.RemoveIf(func(n subscribe.Notification) bool {
+		return groupKey(n) == key
+	})
+	if removed > 0 {
+		publishHistory(notificationHistory.List())
 	}
-	saveHistoryList(newHistory)
-	runEWWUpdate("NOTIFICATION_HISTORY", newHistory)
+	return max(closed, removed)
 }
 
 // CloseNotificationViewOnly removes from active view but keeps in history
 func (h *notificationHelper) CloseNotificationViewOnly(id uint32) {
 	activeNotifications.mu.Lock()
 	delete(activeNotifications.data, id)
+	cancelExpiry(id)
 	snapshot := getSortedSnapshot() // Use sorted snapshot
 	activeNotifications.mu.Unlock()
 
 	// This sends the updated list (minus the closed one) to EWW.
 	// Because it's sorted and complete, it won't clear others.
-	runEWWUpdate("NOTIFICATION", snapshot)
-}
-
-// InvokeAction is called by the UI (EWW) to trigger an action on the app
-func (h *notificationHelper) InvokeAction(id uint32, actionKey string) error {
-	if dbusConn == nil {
-		return errors.New("dbus connection not established")
-	}
-
-	log.Printf("Invoking action: ID=%d, Key=%s", id, actionKey)
-
-	// We must emit the signal on the existing connection that owns the name
-	// org.freedesktop.Notifications
-	err := dbusConn.Emit(
-		"/org/freedesktop/Notifications",
-		"org.freedesktop.Notifications.ActionInvoked",
-		id,
-		actionKey,
-	)
-
-	if err != nil {
-		log.Printf("Failed to emit ActionInvoked: %v", err)
-		return err
-	}
-
-	// Usually, after an action is invoked, the notification should close
-	h.CloseNotificationViewOnly(id)
-
-	// Also signal that the notification is closed due to user action (Reason 2)
-	dbusConn.Emit(
-		"/org/freedesktop/Notifications",
-		"org.freedesktop.Notifications.NotificationClosed",
-		id,
-		uint32(2),
-	)
-
-	return nil
+	publishActive(snapshot)
 }
 
 // SendTestNotification sends a robust notification with images and actions for testing
@@ -219,46 +165,67 @@ type NotificationDaemon struct{}
 
 func StartNotificationWatcher(execArgs []string) {
 	execArgsGlobal = execArgs
+	if err := serveNotifications(nil); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	var err error
-	dbusConn, err = dbus.ConnectSessionBus()
+// StartNotificationService runs the notification daemon as one of the
+// daemon watchers, until stop is closed.
+func StartNotificationService(stop <-chan struct{}) error {
+	return serveNotifications(stop)
+}
+
+func serveNotifications(stop <-chan struct{}) error {
+	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
-		log.Fatal("DBus connect error:", err)
+		return fmt.Errorf("DBus connect error: %w", err)
 	}
-	// Do not defer close here, this runs forever.
 
-	reply, err := dbusConn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	reply, err := conn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
 	if err != nil {
-		log.Fatal(err)
+		conn.Close()
+		return err
 	}
 	if reply != dbus.RequestNameReplyPrimaryOwner {
-		log.Fatal("Another notification daemon is running. Stop it first.")
+		conn.Close()
+		return errors.New("another notification daemon is running. Stop it first")
 	}
 
 	daemon := &NotificationDaemon{}
-	dbusConn.ExportAll(daemon, "/org/freedesktop/Notifications", "org.freedesktop.Notifications")
+	conn.ExportAll(daemon, "/org/freedesktop/Notifications", "org.freedesktop.Notifications")
+	if err := exportControl(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	dbusConn.Store(conn)
 
-	history := loadHistory()
-	if len(history) > 0 {
-		runEWWUpdate("NOTIFICATION_HISTORY", history)
+	if history := notificationHistory.List(); len(history) > 0 {
+		publishHistory(history)
 	}
 
+	go watchDND(stop)
+	go collectImages()
+
 	log.Println("Notification daemon started on org.freedesktop.Notifications")
-	select {}
+	<-stop // a nil stop blocks forever
+
+	dbusConn.Store(nil)
+	conn.ReleaseName("org.freedesktop.Notifications")
+	conn.ReleaseName(controlName)
+	return conn.Close()
 }
 
 func (n *NotificationDaemon) Notify(appName string, replacesID uint32, appIcon, summary, body string,
 	actions []string, hints map[string]dbus.Variant, expireTimeout int32) uint32 {
 
-	var id uint32
-	if replacesID != 0 {
-		id = replacesID
-	} else {
-		nextNotificationID := uint32(time.Now().UnixNano() & 0x7fffffff)
-		id = atomic.AddUint32(&nextNotificationID, 1)
-		if id == 0 {
-			id = atomic.AddUint32(&nextNotificationID, 1)
-		}
+	// A notification replaced through replaces_id keeps its ID. One
+	// replaced through x-canonical-private-synchronous gets a new ID, and the
+	// old one is reported closed.
+	id, replaced := replacementID(replacesID)
+	superseded := synchronousID(appName, hints)
+	if id == 0 {
+		id = newNotificationID()
 	}
 
 	// Urgency: 0=low, 1=normal, 2=critical
@@ -267,10 +234,30 @@ func (n *NotificationDaemon) Notify(appName string, replacesID uint32, appIcon,
 		urgency = u
 	}
 
-	imagePath := appIcon
-	processedImg, err := extractImageFromHints(hints, id)
-	if err == nil && processedImg != "" {
-		imagePath = processedImg
+	rules := applyRules(subscribe.Notification{
+		ID:      id,
+		AppName: appName,
+		AppIcon: appIcon,
+		Summary: summary,
+		Body:    body,
+		Hints:   hints,
+	}, urgency)
+	if rules.drop {
+		log.Printf("Dropped notification %d from %s by rule", id, appName)
+		return id
+	}
+	if rules.urgency >= 0 {
+		urgency = byte(rules.urgency)
+		if hints == nil {
+			hints = make(map[string]dbus.Variant)
+		}
+		hints["urgency"] = dbus.MakeVariant(urgency)
+	}
+
+	imagePath, err := notificationImage(hints, appIcon, appName)
+	if err != nil {
+		log.Printf("notification %d from %s: %v", id, appName, err)
+		imagePath = iconFile(appIcon, imageMaxSize())
 	}
 
 	finalTimeout := expireTimeout
@@ -286,6 +273,9 @@ func (n *NotificationDaemon) Notify(appName string, replacesID uint32, appIcon,
 	} else if urgency == 2 {
 		finalTimeout = 0
 	}
+	if rules.timeout != nil {
+		finalTimeout = int32(rules.timeout.Milliseconds())
+	}
 
 	notif := subscribe.Notification{
 		ID:            id,
@@ -298,115 +288,145 @@ func (n *NotificationDaemon) Notify(appName string, replacesID uint32, appIcon,
 		Hints:         hints,
 		ExpireTimeout: finalTimeout,
 		Timestamp:     time.Now().Unix(),
+		Resident:      hintBool(hints, "resident"),
+		Transient:     hintBool(hints, "transient"),
+		ActionIcons:   hintBool(hints, "action-icons"),
+		SoundFile:     hintString(hints, "sound-file"),
+		SoundName:     hintString(hints, "sound-name"),
+		SuppressSound: hintBool(hints, "suppress-sound"),
 	}
+	notif.Reply = replyAction(notif)
 
-	activeNotifications.mu.Lock()
-	activeNotifications.data[id] = notif
-	snapshot := getSortedSnapshot()
-	activeNotifications.mu.Unlock()
+	bus.Publish("NOTIFICATION_RECEIVED", notif)
 
-	appendHistory(notif)
-	runEWWUpdate("NOTIFICATION_HISTORY", loadHistory())
+	// Transient notifications bypass persistence
+	if !rules.skipHistory && !notif.Transient {
+		notificationHistory.Add(notif)
+		publishHistory(notificationHistory.List())
+	}
 
-	if !NotificationHelper.IsDND() {
-		runEWWUpdate("NOTIFICATION", snapshot)
+	if superseded != 0 && superseded != id {
+		closeActive(superseded, closeUndefined)
 	}
 
-	if finalTimeout > 0 {
-		go scheduleAutoClear(id, finalTimeout)
+	if rules.hidePopup {
+		// An update may hide a popup that is showing
+		if replaced {
+			closeActive(id, closeUndefined)
+		}
+	} else {
+		// Held back before it is added, so no snapshot shows it during DND
+		held := suppressed(urgency)
+		if held {
+			holdBack(notif)
+		} else {
+			release(id)
+		}
+
+		activeNotifications.mu.Lock()
+		activeNotifications.data[id] = notif
+		scheduleExpiry(id, finalTimeout)
+		snapshot := getSortedSnapshot()
+		activeNotifications.mu.Unlock()
+
+		if !held {
+			publishActive(snapshot)
+			playNotificationSound(notif, urgency)
+		}
 	}
 
 	if len(execArgsGlobal) > 0 {
 		go runCommand(execArgsGlobal, notif)
 	}
+	for _, command := range rules.exec {
+		go runShell(command, notif)
+	}
+	runHooks(notif, urgency)
 
 	return id
 }
 
-func getSortedSnapshot() []subscribe.Notification {
-	snapshot := make([]subscribe.Notification, 0, len(activeNotifications.data))
-	for _, n := range activeNotifications.data {
-		snapshot = append(snapshot, n)
-	}
-
-	sort.Slice(snapshot, func(i, j int) bool {
-		return snapshot[i].ID > snapshot[j].ID
+// newNotificationID hands out increasing IDs, starting after the newest one
+// in history so IDs the UI still shows are not reused. 0 is skipped, the spec
+// reserves it.
+func newNotificationID() uint32 {
+	seedNotificationID.Do(func() {
+		if history := notificationHistory.List(); len(history) > 0 {
+			lastNotificationID.Store(history[0].ID)
+		}
 	})
 
-	return snapshot
+	id := lastNotificationID.Add(1)
+	if id == 0 {
+		id = lastNotificationID.Add(1)
+	}
+	return id
 }
 
-func extractImageFromHints(hints map[string]dbus.Variant, id uint32) (string, error) {
-
-	if path, ok := hints["image-path"].Value().(string); ok && path != "" {
-		return path, nil
+// replacementID returns replacesID if it names a notification still known,
+// in the popup list or in history.
+func replacementID(replacesID uint32) (uint32, bool) {
+	if replacesID == 0 {
+		return 0, false
 	}
-	if path, ok := hints["image_path"].Value().(string); ok && path != "" {
-		return path, nil
+
+	activeNotifications.mu.Lock()
+	_, ok := activeNotifications.data[replacesID]
+	activeNotifications.mu.Unlock()
+	if ok {
+		return replacesID, true
 	}
 
-	var imgDataStruct []any
+	if _, ok := notificationHistory.Get(replacesID); ok {
+		return replacesID, true
+	}
+	return 0, false
+}
 
-	if val, ok := hints["image-data"]; ok {
-		if s, ok := val.Value().([]any); ok {
-			imgDataStruct = s
-		}
-	} else if val, ok := hints["icon_data"]; ok {
+// synchronousID returns the showing notification of appName sharing the
+// x-canonical-private-synchronous hint, which the new one takes the place of.
+func synchronousID(appName string, hints map[string]dbus.Variant) uint32 {
+	tag := hintString(hints, "x-canonical-private-synchronous")
+	if tag == "" {
+		return 0
+	}
 
-		if s, ok := val.Value().([]any); ok {
-			imgDataStruct = s
+	activeNotifications.mu.Lock()
+	defer activeNotifications.mu.Unlock()
+	for id, n := range activeNotifications.data {
+		if n.AppName == appName && hintString(n.Hints, "x-canonical-private-synchronous") == tag {
+			return id
 		}
 	}
+	return 0
+}
 
-	if len(imgDataStruct) == 7 {
-		width := imgDataStruct[0].(int32)
-		height := imgDataStruct[1].(int32)
-		// rowstride := imgDataStruct[2].(int32)
-		hasAlpha := imgDataStruct[3].(bool)
-		// bitsPerSample := imgDataStruct[4].(int32) // mostly 8
-		channels := imgDataStruct[5].(int32)
-		pixelData := imgDataStruct[6].([]byte)
-
-		img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
-
-		ptr := 0
-		for y := 0; y < int(height); y++ {
-			for x := 0; x < int(width); x++ {
-				if ptr+int(channels) > len(pixelData) {
-					break
-				}
-
-				r := pixelData[ptr]
-				g := pixelData[ptr+1]
-				b := pixelData[ptr+2]
-				a := uint8(255)
-				if hasAlpha && channels > 3 {
-					a = pixelData[ptr+3]
-				}
-
-				img.Set(x, y, color.RGBA{R: r, G: g, B: b, A: a})
-				ptr += int(channels)
-			}
-			// Skip rowstride padding if any (rowstride is bytes per row)
-			// ptr should align with rowstride, but simple iteration often works for packed data
-			// A strict implementation would jump ptr based on rowstride
-		}
+func hintBool(hints map[string]dbus.Variant, key string) bool {
+	b, _ := hints[key].Value().(bool)
+	return b
+}
 
-		// Save to cache
-		fileName := filepath.Join(cacheDir, fmt.Sprintf("%d.png", id))
-		f, err := os.Create(fileName)
-		if err != nil {
-			return "", err
-		}
-		defer f.Close()
+func hintString(hints map[string]dbus.Variant, key string) string {
+	s, _ := hints[key].Value().(string)
+	return s
+}
 
-		if err := png.Encode(f, img); err != nil {
-			return "", err
+// getSortedSnapshot returns the popup list, newest first, without what DND
+// holds back. Callers hold activeNotifications.mu.
+func getSortedSnapshot() []subscribe.Notification {
+	held := heldIDs()
+	snapshot := make([]subscribe.Notification, 0, len(activeNotifications.data))
+	for _, n := range activeNotifications.data {
+		if !held[n.ID] {
+			snapshot = append(snapshot, n)
 		}
-		return fileName, nil
 	}
 
-	return "", nil
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].ID > snapshot[j].ID
+	})
+
+	return snapshot
 }
 
 func (n *NotificationDaemon) GetServerInformation() (string, string, string, string) {
@@ -414,107 +434,101 @@ func (n *NotificationDaemon) GetServerInformation() (string, string, string, str
 }
 
 func (n *NotificationDaemon) GetCapabilities() []string {
-	return []string{"body", "body-markup", "actions", "icon-static", "persistence"}
+	return []string{"action-icons", "actions", "body", "body-markup", "icon-static", "inline-reply", "persistence", "sound"}
 }
 
-func (n *NotificationDaemon) CloseNotification(id uint32) {
-	activeNotifications.mu.Lock()
-	delete(activeNotifications.data, id)
-	snapshot := getSortedSnapshot()
-	activeNotifications.mu.Unlock()
-
-	runEWWUpdate("NOTIFICATION", snapshot)
-
-	if dbusConn != nil {
-		dbusConn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.NotificationClosed", id, uint32(3)) // 3 = closed by call
+// CloseNotification closes a notification on behalf of its app. The spec
+// wants an empty error back when it is not showing.
+func (n *NotificationDaemon) CloseNotification(id uint32) *dbus.Error {
+	if !closeActive(id, closeByCall) {
+		return dbus.NewError("org.freedesktop.Notifications.Error.NotFound", nil)
 	}
+	return nil
 }
 
-func scheduleAutoClear(id uint32, timeout int32) {
-	time.Sleep(time.Duration(timeout) * time.Millisecond)
-
+// closeActive removes a notification from the popup list and reports it
+// closed. It returns false if it was not showing.
+func closeActive(id uint32, reason uint32) bool {
 	activeNotifications.mu.Lock()
-	if _, exists := activeNotifications.data[id]; !exists {
-		activeNotifications.mu.Unlock()
-		return
-	}
-	delete(activeNotifications.data, id)
-	snapshot := getSortedSnapshot()
+	snapshot, ok := removeActive(id)
 	activeNotifications.mu.Unlock()
-
-	if !NotificationHelper.IsDND() {
-		runEWWUpdate("NOTIFICATION", snapshot)
+	if ok {
+		announceClosed(id, reason, snapshot)
 	}
+	return ok
+}
 
-	if dbusConn != nil {
-		dbusConn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.NotificationClosed", id, uint32(1))
+// removeActive drops id from the popup list, and from what DND held back,
+// and returns what is left. Callers hold activeNotifications.mu.
+func removeActive(id uint32) ([]subscribe.Notification, bool) {
+	if _, ok := activeNotifications.data[id]; !ok {
+		return nil, false
 	}
+	delete(activeNotifications.data, id)
+	cancelExpiry(id)
+	release(id)
+	return getSortedSnapshot(), true
 }
 
-func appendHistory(n subscribe.Notification) {
-	dir := filepath.Dir(historyPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Println("failed to create history directory:", err)
-		return
-	}
+func announceClosed(id uint32, reason uint32, snapshot []subscribe.Notification) {
+	publishActive(snapshot)
+	emitClosed(id, reason)
+}
 
-	f, err := os.OpenFile(historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Println("history write error:", err)
+// scheduleExpiry (re)arms the timer closing id after timeout milliseconds.
+// Callers hold activeNotifications.mu.
+func scheduleExpiry(id uint32, timeout int32) {
+	cancelExpiry(id)
+	if timeout <= 0 {
 		return
 	}
-	defer f.Close()
 
-	data, _ := json.Marshal(n)
-	f.Write(data)
-	f.Write([]byte("\n"))
+	expiry.mu.Lock()
+	defer expiry.mu.Unlock()
+	var t *time.Timer
+	t = time.AfterFunc(time.Duration(timeout)*time.Millisecond, func() { expire(id, t) })
+	expiry.timers[id] = t
 }
 
-func saveHistoryList(history []subscribe.Notification) {
-	dir := filepath.Dir(historyPath)
-	os.MkdirAll(dir, 0755)
-	f, err := os.OpenFile(historyPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		fmt.Println("Error updating history:", err)
+// expire closes id when its timer t fires, unless an update re-armed it.
+func expire(id uint32, t *time.Timer) {
+	activeNotifications.mu.Lock()
+	expiry.mu.Lock()
+	current := expiry.timers[id] == t
+	expiry.mu.Unlock()
+	if !current {
+		activeNotifications.mu.Unlock()
 		return
 	}
-	defer f.Close()
-	for _, n := range history {
-		data, _ := json.Marshal(n)
-		f.Write(data)
-		f.Write([]byte("\n"))
-	}
-}
+	snapshot, ok := removeActive(id)
+	activeNotifications.mu.Unlock()
 
-func loadHistory() []subscribe.Notification {
-	data, err := os.ReadFile(historyPath)
-	if err != nil {
-		return nil
+	if ok {
+		announceClosed(id, closeExpired, snapshot)
 	}
+}
 
-	var history []subscribe.Notification
-	lines := bytes.SplitSeq(data, []byte("\n"))
-	for l := range lines {
-		if len(l) == 0 {
-			continue
-		}
-		var notif subscribe.Notification
-		if err := json.Unmarshal(l, &notif); err == nil {
-			history = append(history, notif)
-		}
+func cancelExpiry(id uint32) {
+	expiry.mu.Lock()
+	defer expiry.mu.Unlock()
+	if t, ok := expiry.timers[id]; ok {
+		t.Stop()
+		delete(expiry.timers, id)
 	}
+}
 
-	sort.Slice(history, func(i, j int) bool { return history[i].ID > history[j].ID })
-
-	return history
+type notificationClosedEvent struct {
+	ID     uint32 `json:"id"`
+	Reason uint32 `json:"reason"`
 }
 
-func runEWWUpdate(varName string, data any) {
-	jsonBytes, _ := json.Marshal(data)
-	cmd := exec.Command("eww", "update", varName+"="+string(jsonBytes))
-	if err := cmd.Run(); err != nil {
-		log.Printf("eww update error: %v", err)
+// emitClosed sends NotificationClosed on the bus and publishes it to
+// subscribers of the daemon.
+func emitClosed(id uint32, reason uint32) {
+	if conn := dbusConn.Load(); conn != nil {
+		conn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.NotificationClosed", id, reason)
 	}
+	bus.Publish("NOTIFICATION_CLOSED", notificationClosedEvent{ID: id, Reason: reason})
 }
 
 func runCommand(args []string, n subscribe.Notification) {
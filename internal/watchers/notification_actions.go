@@ -0,0 +1,139 @@
+package watchers
+
+import (
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/hoppxi/wigo/internal/bus"
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+// inlineReplyAction is the action key of apps taking inline replies.
+const inlineReplyAction = "inline-reply"
+
+type notificationActionEvent struct {
+	ID     uint32 `json:"id"`
+	Action string `json:"action"`
+}
+
+type notificationReplyEvent struct {
+	ID   uint32 `json:"id"`
+	Text string `json:"text"`
+}
+
+// replyAction returns the text entry to show for n, if its app takes inline
+// replies.
+func replyAction(n subscribe.Notification) *subscribe.ReplyAction {
+	for i := 0; i+1 < len(n.Actions); i += 2 {
+		if n.Actions[i] == inlineReplyAction {
+			return &subscribe.ReplyAction{
+				Label:       n.Actions[i+1],
+				Placeholder: hintString(n.Hints, "x-kde-reply-placeholder-text"),
+				Submit:      hintString(n.Hints, "x-kde-reply-submit-button-text"),
+			}
+		}
+	}
+	return nil
+}
+
+// InvokeAction is called by the UI (EWW) to trigger an action on the app
+func (h *notificationHelper) InvokeAction(id uint32, actionKey string) error {
+	return h.InvokeActionWithToken(id, actionKey, "")
+}
+
+// InvokeActionWithToken invokes an action and first hands the app an XDG
+// activation token, so it may raise its window.
+func (h *notificationHelper) InvokeActionWithToken(id uint32, actionKey, token string) error {
+	conn := dbusConn.Load()
+	if conn == nil {
+		return errors.New("dbus connection not established")
+	}
+	if actionKey == inlineReplyAction {
+		return errors.New("inline-reply takes a reply, not an action")
+	}
+
+	log.Printf("Invoking action: ID=%d, Key=%s", id, actionKey)
+
+	// The token has to arrive before ActionInvoked
+	if token != "" {
+		err := conn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.ActivationToken", id, token)
+		if err != nil {
+			log.Printf("Failed to emit ActivationToken: %v", err)
+			return err
+		}
+	}
+
+	// We must emit the signal on the existing connection that owns the name
+	// org.freedesktop.Notifications
+	err := conn.Emit(
+		"/org/freedesktop/Notifications",
+		"org.freedesktop.Notifications.ActionInvoked",
+		id,
+		actionKey,
+	)
+
+	if err != nil {
+		log.Printf("Failed to emit ActionInvoked: %v", err)
+		return err
+	}
+	bus.Publish("NOTIFICATION_ACTION", notificationActionEvent{ID: id, Action: actionKey})
+
+	afterAction(id)
+	return nil
+}
+
+// Reply sends text to the app of a notification with an inline-reply
+// action.
+func (h *notificationHelper) Reply(id uint32, text string) error {
+	conn := dbusConn.Load()
+	if conn == nil {
+		return errors.New("dbus connection not established")
+	}
+
+	n, ok := findNotification(id)
+	if !ok {
+		return errors.New("no such notification")
+	}
+	if !slices.Contains(n.Actions, inlineReplyAction) {
+		return errors.New("the notification does not take replies")
+	}
+
+	err := conn.Emit("/org/freedesktop/Notifications", "org.freedesktop.Notifications.NotificationReplied", id, text)
+	if err != nil {
+		log.Printf("Failed to emit NotificationReplied: %v", err)
+		return err
+	}
+	bus.Publish("NOTIFICATION_REPLIED", notificationReplyEvent{ID: id, Text: text})
+
+	afterAction(id)
+	return nil
+}
+
+// afterAction closes a notification once it was acted on, unless it is
+// resident or notifications.dismiss_on_action is off.
+func afterAction(id uint32) {
+	if dismiss := notificationConfig().DismissOnAction; dismiss != nil && !*dismiss {
+		return
+	}
+
+	activeNotifications.mu.Lock()
+	n, ok := activeNotifications.data[id]
+	activeNotifications.mu.Unlock()
+	if ok && n.Resident {
+		return
+	}
+
+	closeActive(id, closeDismissed)
+}
+
+// findNotification looks id up in the popup list, then in history.
+func findNotification(id uint32) (subscribe.Notification, bool) {
+	activeNotifications.mu.Lock()
+	n, ok := activeNotifications.data[id]
+	activeNotifications.mu.Unlock()
+	if ok {
+		return n, true
+	}
+	return notificationHistory.Get(id)
+}
@@ -0,0 +1,148 @@
+package watchers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+// NotificationClient talks to the running notification daemon over
+// org.wigo.Notifications, so commands act on the state it owns.
+type NotificationClient struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+}
+
+// DialNotifications connects to the notification daemon on the session bus.
+func DialNotifications() (*NotificationClient, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	var running bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, controlName).Store(&running); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !running {
+		conn.Close()
+		return nil, errors.New("the notification daemon is not running; enable the notification watcher or run `wigo notification`")
+	}
+
+	return &NotificationClient{conn: conn, obj: conn.Object(controlName, controlPath)}, nil
+}
+
+func (c *NotificationClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *NotificationClient) call(method string, args ...any) *dbus.Call {
+	return c.obj.Call(controlIface+"."+method, 0, args...)
+}
+
+// DismissAll closes every showing notification and returns how many.
+func (c *NotificationClient) DismissAll() (int, error) {
+	var n uint32
+	err := c.call("DismissAll").Store(&n)
+	return int(n), err
+}
+
+func (c *NotificationClient) Dismiss(id uint32, keepHistory bool) error {
+	return c.call("Dismiss", id, keepHistory).Err
+}
+
+func (c *NotificationClient) DismissGroup(key string, keepHistory bool) (int, error) {
+	var n uint32
+	err := c.call("DismissGroup", key, keepHistory).Store(&n)
+	return int(n), err
+}
+
+func (c *NotificationClient) History(q HistoryQuery) ([]subscribe.Notification, error) {
+	query := map[string]dbus.Variant{}
+	if q.App != "" {
+		query["app"] = dbus.MakeVariant(q.App)
+	}
+	if !q.Since.IsZero() {
+		query["since"] = dbus.MakeVariant(q.Since.Unix())
+	}
+	if q.Grep != nil {
+		query["grep"] = dbus.MakeVariant(q.Grep.String())
+	}
+	if q.Limit > 0 {
+		query["limit"] = dbus.MakeVariant(uint32(q.Limit))
+	}
+
+	var data string
+	if err := c.call("GetHistory", query).Store(&data); err != nil {
+		return nil, err
+	}
+	var list []subscribe.Notification
+	err := json.Unmarshal([]byte(data), &list)
+	return list, err
+}
+
+func (c *NotificationClient) HistoryCount() (int, error) {
+	var n uint32
+	err := c.call("GetHistoryCount").Store(&n)
+	return int(n), err
+}
+
+func (c *NotificationClient) ClearHistory() error {
+	return c.call("ClearHistory").Err
+}
+
+// SetDND takes "on", "off" or "toggle" and returns whether DND is on.
+func (c *NotificationClient) SetDND(state string) (bool, error) {
+	var on bool
+	err := c.call("SetDND", state).Store(&on)
+	return on, err
+}
+
+func (c *NotificationClient) SetDNDFor(d time.Duration) error {
+	if d <= 0 {
+		return errors.New("duration must be positive")
+	}
+	return c.call("SetDNDFor", uint32(d.Round(time.Second)/time.Second)).Err
+}
+
+func (c *NotificationClient) DND() (bool, error) {
+	var (
+		on     bool
+		reason string
+		until  int64
+	)
+	err := c.call("GetDND").Store(&on, &reason, &until)
+	return on, err
+}
+
+// InvokeAction invokes an action; token, if set, is passed on to the app
+// as its XDG activation token.
+func (c *NotificationClient) InvokeAction(id uint32, action, token string) error {
+	return c.call("InvokeActionWithToken", id, action, token).Err
+}
+
+func (c *NotificationClient) Reply(id uint32, text string) error {
+	return c.call("Reply", id, text).Err
+}
+
+// Notify sends a notification through org.freedesktop.Notifications and
+// returns its ID.
+func (c *NotificationClient) Notify(appName string, replacesID uint32, appIcon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, error) {
+	if hints == nil {
+		hints = map[string]dbus.Variant{}
+	}
+	if actions == nil {
+		actions = []string{}
+	}
+
+	var id uint32
+	err := c.conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications").
+		Call("org.freedesktop.Notifications.Notify", 0,
+			appName, replacesID, appIcon, summary, body, actions, hints, expireTimeout).Store(&id)
+	return id, err
+}
@@ -0,0 +1,68 @@
+package watchers
+
+import (
+	"log"
+	"sync"
+)
+
+// NotificationSettings is the `notifications:` section of wigo.yaml.
+type NotificationSettings struct {
+	Rules []NotificationRule `mapstructure:"rules"`
+	// GroupBy splits NOTIFICATION_GROUPS by app (default), category or
+	// synchronous.
+	GroupBy string          `mapstructure:"group_by"`
+	DND     DNDSettings     `mapstructure:"dnd"`
+	History HistorySettings `mapstructure:"history"`
+	// DismissOnAction closes a notification once an action was invoked or
+	// a reply sent, on by default. Resident notifications always stay.
+	DismissOnAction *bool              `mapstructure:"dismiss_on_action"`
+	Sound           SoundSettings      `mapstructure:"sound"`
+	Images          ImageSettings      `mapstructure:"images"`
+	Hooks           []NotificationHook `mapstructure:"hooks"`
+}
+
+var notificationSettings = struct {
+	mu    sync.RWMutex
+	s     NotificationSettings
+	rules []compiledRule
+	hooks []compiledHook
+}{}
+
+// ConfigureNotifications replaces the notification settings. Rules and hooks
+// that do not compile are logged and left out.
+func ConfigureNotifications(s NotificationSettings) {
+	rules := make([]compiledRule, 0, len(s.Rules))
+	for i, r := range s.Rules {
+		c, err := compileRule(r)
+		if err != nil {
+			log.Printf("notification rule %d (%s): %v", i, r.Name, err)
+			continue
+		}
+		rules = append(rules, c)
+	}
+
+	hooks := make([]compiledHook, 0, len(s.Hooks))
+	for i, h := range s.Hooks {
+		c, err := compileHook(h)
+		if err != nil {
+			log.Printf("notification hook %d (%s): %v", i, h.Name, err)
+			continue
+		}
+		hooks = append(hooks, c)
+	}
+
+	windows := compileWindows(s.DND.Schedule)
+	dndWindows.Store(&windows)
+
+	notificationSettings.mu.Lock()
+	notificationSettings.s = s
+	notificationSettings.rules = rules
+	notificationSettings.hooks = hooks
+	notificationSettings.mu.Unlock()
+}
+
+func notificationConfig() NotificationSettings {
+	notificationSettings.mu.RLock()
+	defer notificationSettings.mu.RUnlock()
+	return notificationSettings.s
+}
@@ -0,0 +1,200 @@
+package watchers
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+// org.wigo.Notifications lets the CLI and other tools control the daemon
+// that owns the notification state.
+const (
+	controlName  = "org.wigo.Notifications"
+	controlPath  = dbus.ObjectPath("/org/wigo/Notifications")
+	controlIface = "org.wigo.Notifications"
+)
+
+// NotificationControl is exported as org.wigo.Notifications.
+type NotificationControl struct{}
+
+func controlError(err error) *dbus.Error {
+	return dbus.NewError(controlIface+".Error.Failed", []any{err.Error()})
+}
+
+// DismissAll closes every showing notification, keeping them in history.
+func (c *NotificationControl) DismissAll() (uint32, *dbus.Error) {
+	activeNotifications.mu.Lock()
+	ids := make([]uint32, 0, len(activeNotifications.data))
+	for id := range activeNotifications.data {
+		ids = append(ids, id)
+	}
+	activeNotifications.mu.Unlock()
+
+	var closed uint32
+	for _, id := range ids {
+		if closeActive(id, closeDismissed) {
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+// Dismiss closes one notification, and removes it from history unless
+// keepHistory is set.
+func (c *NotificationControl) Dismiss(id uint32, keepHistory bool) *dbus.Error {
+	found := closeActive(id, closeDismissed)
+	if !keepHistory && notificationHistory.Remove(id) {
+		found = true
+		publishHistory(notificationHistory.List())
+	}
+	if !found {
+		return dbus.NewError(controlIface+".Error.NotFound", []any{"no such notification"})
+	}
+	return nil
+}
+
+// DismissGroup closes every notification of a group, see
+// NOTIFICATION_GROUPS, and removes the group from history unless
+// keepHistory is set.
+func (c *NotificationControl) DismissGroup(key string, keepHistory bool) (uint32, *dbus.Error) {
+	if keepHistory {
+		return uint32(NotificationHelper.CloseGroup(key)), nil
+	}
+	return uint32(NotificationHelper.DismissGroup(key)), nil
+}
+
+// GetHistory returns the history matching query as a JSON array, newest
+// first. query takes "app" (s, glob), "since" (x, unix time), "grep" (s,
+// regular expression) and "limit" (u).
+func (c *NotificationControl) GetHistory(query map[string]dbus.Variant) (string, *dbus.Error) {
+	var q HistoryQuery
+	q.App, _ = query["app"].Value().(string)
+	if since, ok := query["since"].Value().(int64); ok && since > 0 {
+		q.Since = time.Unix(since, 0)
+	}
+	if grep, ok := query["grep"].Value().(string); ok && grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return "", controlError(err)
+		}
+		q.Grep = re
+	}
+	if limit, ok := query["limit"].Value().(uint32); ok {
+		q.Limit = int(limit)
+	}
+
+	data, err := json.Marshal(notificationHistory.Query(q))
+	if err != nil {
+		return "", controlError(err)
+	}
+	return string(data), nil
+}
+
+func (c *NotificationControl) GetHistoryCount() (uint32, *dbus.Error) {
+	return uint32(notificationHistory.Count()), nil
+}
+
+func (c *NotificationControl) ClearHistory() *dbus.Error {
+	if err := NotificationHelper.ClearHistory(); err != nil {
+		return controlError(err)
+	}
+	return nil
+}
+
+// SetDND turns DND "on", "off" or flips it with "toggle", and returns
+// whether it is on afterwards.
+func (c *NotificationControl) SetDND(state string) (bool, *dbus.Error) {
+	var err error
+	switch state {
+	case "toggle":
+		NotificationHelper.ToggleDND()
+	default:
+		err = NotificationHelper.SetDNDState(state)
+	}
+	if err != nil {
+		return false, controlError(err)
+	}
+	refreshDND()
+	return NotificationHelper.IsDND(), nil
+}
+
+// SetDNDFor turns DND on for a number of seconds.
+func (c *NotificationControl) SetDNDFor(seconds uint32) *dbus.Error {
+	if err := NotificationHelper.SetDNDFor(time.Duration(seconds) * time.Second); err != nil {
+		return controlError(err)
+	}
+	refreshDND()
+	return nil
+}
+
+// GetDND returns whether DND is on, why, and until when (unix time, 0 if
+// open ended).
+func (c *NotificationControl) GetDND() (bool, string, int64, *dbus.Error) {
+	s := currentDND(time.Now())
+	return s.Active, s.Reason, s.Until, nil
+}
+
+func (c *NotificationControl) InvokeAction(id uint32, action string) *dbus.Error {
+	return c.InvokeActionWithToken(id, action, "")
+}
+
+// InvokeActionWithToken invokes an action, handing the app an XDG
+// activation token first.
+func (c *NotificationControl) InvokeActionWithToken(id uint32, action, token string) *dbus.Error {
+	if err := NotificationHelper.InvokeActionWithToken(id, action, token); err != nil {
+		return controlError(err)
+	}
+	return nil
+}
+
+// Reply answers a notification with an inline-reply action.
+func (c *NotificationControl) Reply(id uint32, text string) *dbus.Error {
+	if err := NotificationHelper.Reply(id, text); err != nil {
+		return controlError(err)
+	}
+	return nil
+}
+
+var controlIntrospection = &introspect.Node{
+	Name: string(controlPath),
+	Interfaces: []introspect.Interface{
+		introspect.IntrospectData,
+		{
+			Name:    controlIface,
+			Methods: introspect.Methods(&NotificationControl{}),
+			Signals: []introspect.Signal{{
+				Name: "HistoryChanged",
+				Args: []introspect.Arg{{Name: "count", Type: "u"}},
+			}},
+		},
+	},
+}
+
+// exportControl serves org.wigo.Notifications on conn.
+func exportControl(conn *dbus.Conn) error {
+	reply, err := conn.RequestName(controlName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return errors.New(controlName + " is taken by another wigo")
+	}
+
+	if err := conn.Export(&NotificationControl{}, controlPath, controlIface); err != nil {
+		return err
+	}
+	return conn.Export(introspect.NewIntrospectable(controlIntrospection), controlPath, "org.freedesktop.DBus.Introspectable")
+}
+
+// emitHistoryChanged tells org.wigo.Notifications listeners the history
+// changed.
+func emitHistoryChanged(history []subscribe.Notification) {
+	if conn := dbusConn.Load(); conn != nil {
+		conn.Emit(controlPath, controlIface+".HistoryChanged", uint32(len(history)))
+	}
+}
@@ -0,0 +1,398 @@
+package watchers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/bus"
+	"github.com/hoppxi/wigo/internal/subscribe"
+	"github.com/hoppxi/wigo/pkg/workspace"
+)
+
+// DNDSettings is the `notifications.dnd` section of wigo.yaml.
+type DNDSettings struct {
+	// Schedule lists daily windows such as 22:00-07:00.
+	Schedule []DNDWindow `mapstructure:"schedule"`
+	// Fullscreen turns DND on while a fullscreen window is focused.
+	Fullscreen bool `mapstructure:"fullscreen"`
+	// AllowCritical lets critical notifications through, on by default.
+	AllowCritical *bool `mapstructure:"allow_critical"`
+	// Summary shows one notification listing what was held back once DND
+	// ends, on by default.
+	Summary *bool `mapstructure:"summary"`
+}
+
+// DNDWindow is a daily DND period. Days limits it to some weekdays (mon,
+// tue...); a window past midnight belongs to the day it starts on.
+type DNDWindow struct {
+	From string   `mapstructure:"from"`
+	To   string   `mapstructure:"to"`
+	Days []string `mapstructure:"days"`
+}
+
+type compiledWindow struct {
+	from, to int // minutes since midnight
+	days     []time.Weekday
+}
+
+// dndStatus is published as NOTIFICATION_DND.
+type dndStatus struct {
+	Active bool   `json:"active"`
+	Reason string `json:"reason,omitempty"`
+	Until  int64  `json:"until,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+var (
+	dndWindows    atomic.Pointer[[]compiledWindow]
+	dndFullscreen atomic.Bool
+	// dndChanged wakes watchDND when DND was set by hand
+	dndChanged = make(chan struct{}, 1)
+
+	// heldBack collects what DND kept from popping up, for the summary.
+	heldBack = struct {
+		mu    sync.Mutex
+		items []subscribe.Notification
+	}{}
+)
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func compileWindows(windows []DNDWindow) []compiledWindow {
+	out := make([]compiledWindow, 0, len(windows))
+	for _, w := range windows {
+		from, err := parseClock(w.From)
+		if err != nil {
+			log.Printf("dnd schedule: %v", err)
+			continue
+		}
+		to, err := parseClock(w.To)
+		if err != nil {
+			log.Printf("dnd schedule: %v", err)
+			continue
+		}
+
+		c := compiledWindow{from: from, to: to}
+		for _, d := range w.Days {
+			wd, ok := parseWeekday(d)
+			if !ok {
+				log.Printf("dnd schedule: unknown day %q", d)
+				continue
+			}
+			c.days = append(c.days, wd)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseWeekday takes a full or abbreviated day name, at least three letters.
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 3 {
+		return 0, false
+	}
+	for name, wd := range weekdays {
+		if strings.HasPrefix(s, name) && strings.HasPrefix(strings.ToLower(wd.String()), s) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+func (w compiledWindow) on(day time.Weekday) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, day)
+}
+
+// active reports whether now falls into the window and when it ends.
+func (w compiledWindow) active(now time.Time) (bool, time.Time) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	cur := now.Hour()*60 + now.Minute()
+	at := func(day time.Time, minutes int) time.Time {
+		return day.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	if w.from <= w.to {
+		if cur >= w.from && cur < w.to && w.on(now.Weekday()) {
+			return true, at(midnight, w.to)
+		}
+		return false, time.Time{}
+	}
+
+	// The window wraps around midnight
+	if cur >= w.from && w.on(now.Weekday()) {
+		return true, at(midnight.AddDate(0, 0, 1), w.to)
+	}
+	if cur < w.to && w.on(now.AddDate(0, 0, -1).Weekday()) {
+		return true, at(midnight, w.to)
+	}
+	return false, time.Time{}
+}
+
+// readDNDFile returns the state set by hand: "on", "off" or a timer.
+func readDNDFile() (manual bool, until time.Time) {
+	data, err := os.ReadFile(dndPath)
+	if err != nil {
+		return false, time.Time{}
+	}
+
+	state := strings.TrimSpace(string(data))
+	if rest, ok := strings.CutPrefix(state, "until "); ok {
+		if sec, err := strconv.ParseInt(rest, 10, 64); err == nil {
+			return false, time.Unix(sec, 0)
+		}
+	}
+	return state == "on", time.Time{}
+}
+
+func writeDNDFile(state string) error {
+	if err := os.MkdirAll(filepath.Dir(dndPath), 0755); err != nil {
+		log.Println("failed to create DND directory:", err)
+		return err
+	}
+	return os.WriteFile(dndPath, []byte(state), 0644)
+}
+
+func currentDND(now time.Time) dndStatus {
+	manual, until := readDNDFile()
+	if manual {
+		return dndStatus{Active: true, Reason: "manual"}
+	}
+	if now.Before(until) {
+		return dndStatus{Active: true, Reason: "timer", Until: until.Unix()}
+	}
+	if notificationConfig().DND.Fullscreen && dndFullscreen.Load() {
+		return dndStatus{Active: true, Reason: "fullscreen"}
+	}
+	if windows := dndWindows.Load(); windows != nil {
+		for _, w := range *windows {
+			if ok, end := w.active(now); ok {
+				return dndStatus{Active: true, Reason: "schedule", Until: end.Unix()}
+			}
+		}
+	}
+	return dndStatus{}
+}
+
+// suppressed reports whether a notification of this urgency must not pop up
+// right now.
+func suppressed(urgency byte) bool {
+	if !currentDND(time.Now()).Active {
+		return false
+	}
+	allow := notificationConfig().DND.AllowCritical
+	return urgency != 2 || (allow != nil && !*allow)
+}
+
+func (h *notificationHelper) SetDNDState(state string) error {
+	switch state {
+	case "on", "off":
+		return writeDNDFile(state)
+	default:
+		return errors.New("use --dnd on|off")
+	}
+}
+
+// SetDNDFor turns DND on for d.
+func (h *notificationHelper) SetDNDFor(d time.Duration) error {
+	if d <= 0 {
+		return errors.New("duration must be positive")
+	}
+	return writeDNDFile(fmt.Sprintf("until %d", time.Now().Add(d).Unix()))
+}
+
+func (h *notificationHelper) IsDND() bool {
+	return currentDND(time.Now()).Active
+}
+
+func (h *notificationHelper) GetDNDState() bool {
+	return h.IsDND()
+}
+
+// ToggleDND flips the DND set by hand. A running timer counts as on.
+func (h *notificationHelper) ToggleDND() bool {
+	manual, until := readDNDFile()
+	if manual || time.Now().Before(until) {
+		writeDNDFile("off")
+		return false
+	}
+	writeDNDFile("on")
+	return true
+}
+
+func refreshDND() {
+	select {
+	case dndChanged <- struct{}{}:
+	default:
+	}
+}
+
+func holdBack(n subscribe.Notification) {
+	heldBack.mu.Lock()
+	heldBack.items = slices.DeleteFunc(heldBack.items, func(h subscribe.Notification) bool { return h.ID == n.ID })
+	heldBack.items = append(heldBack.items, n)
+	heldBack.mu.Unlock()
+}
+
+// release lets id pop up again, once an update of it is let through.
+func release(id uint32) {
+	heldBack.mu.Lock()
+	heldBack.items = slices.DeleteFunc(heldBack.items, func(h subscribe.Notification) bool { return h.ID == id })
+	heldBack.mu.Unlock()
+}
+
+// heldIDs returns the IDs held back, which snapshots leave out until DND
+// ends. It takes heldBack.mu, so it may be called under
+// activeNotifications.mu but not the other way round.
+func heldIDs() map[uint32]bool {
+	heldBack.mu.Lock()
+	defer heldBack.mu.Unlock()
+	ids := make(map[uint32]bool, len(heldBack.items))
+	for _, n := range heldBack.items {
+		ids[n.ID] = true
+	}
+	return ids
+}
+
+// watchDND follows DND changes from the file, the schedule and fullscreen
+// windows, publishes NOTIFICATION_DND and summarizes what was held back when
+// DND ends.
+func watchDND(stop <-chan struct{}) {
+	var events <-chan subscribe.Event
+	if notificationConfig().DND.Fullscreen && os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "" {
+		if ch, err := subscribe.SubscribeEvents(stop); err == nil {
+			events = ch
+			dndFullscreen.Store(workspace.IsActiveFullscreen())
+		} else {
+			log.Printf("dnd: cannot follow fullscreen windows: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	last := currentDND(time.Now())
+	publish("NOTIFICATION_DND", last)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-dndChanged:
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				dndFullscreen.Store(false)
+				break
+			}
+			switch e.Name {
+			case "fullscreen", "activewindow", "workspace", "closewindow":
+				dndFullscreen.Store(workspace.IsActiveFullscreen())
+			default:
+				continue
+			}
+		}
+
+		cur := currentDND(time.Now())
+		if cur == last {
+			continue
+		}
+		publish("NOTIFICATION_DND", cur)
+		if last.Active && !cur.Active {
+			endDND()
+		}
+		last = cur
+	}
+}
+
+// endDND drops the notifications held back during DND from the popup list
+// and shows a single summary of them instead. They were never shown, so
+// apps are told they closed for an undefined reason rather than expired.
+// Those the app closed or that expired during DND left heldBack already
+// and are not counted.
+func endDND() {
+	// Under activeNotifications.mu, so no snapshot sees them released
+	activeNotifications.mu.Lock()
+	heldBack.mu.Lock()
+	items := heldBack.items
+	heldBack.items = nil
+	heldBack.mu.Unlock()
+
+	var held []subscribe.Notification
+	for _, n := range items {
+		if _, ok := removeActive(n.ID); ok {
+			held = append(held, n)
+		}
+	}
+	activeNotifications.mu.Unlock()
+	for _, n := range held {
+		emitClosed(n.ID, closeUndefined)
+	}
+
+	summary := notificationConfig().DND.Summary
+	if len(held) == 0 || (summary != nil && !*summary) {
+		activeNotifications.mu.Lock()
+		snapshot := getSortedSnapshot()
+		activeNotifications.mu.Unlock()
+		publishActive(snapshot)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, n := range held {
+		counts[n.AppName]++
+	}
+	apps := make([]string, 0, len(counts))
+	for app := range counts {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return counts[apps[i]] > counts[apps[j]] })
+
+	parts := make([]string, 0, len(apps))
+	for _, app := range apps {
+		parts = append(parts, fmt.Sprintf("%d from %s", counts[app], app))
+	}
+
+	n := subscribe.Notification{
+		ID:            newNotificationID(),
+		AppName:       "wigo",
+		AppIcon:       iconFile("notifications", imageMaxSize()),
+		Summary:       fmt.Sprintf("%d notifications while Do Not Disturb was on", len(held)),
+		Body:          strings.Join(parts, ", "),
+		Actions:       []string{},
+		Hints:         map[string]dbus.Variant{"urgency": dbus.MakeVariant(byte(0)), "category": dbus.MakeVariant("x-wigo.dnd-summary")},
+		ExpireTimeout: 10000,
+		Timestamp:     time.Now().Unix(),
+	}
+
+	activeNotifications.mu.Lock()
+	activeNotifications.data[n.ID] = n
+	scheduleExpiry(n.ID, n.ExpireTimeout)
+	snapshot := getSortedSnapshot()
+	activeNotifications.mu.Unlock()
+
+	bus.Publish("NOTIFICATION_DND_SUMMARY", n)
+	publishActive(snapshot)
+}
@@ -0,0 +1,187 @@
+package watchers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/bus"
+	"github.com/hoppxi/wigo/internal/sink"
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Weekday
+		ok   bool
+	}{
+		{"mon", time.Monday, true},
+		{"Monday", time.Monday, true},
+		{" SAT ", time.Saturday, true},
+		{"thurs", time.Thursday, true},
+		{"mo", 0, false},
+		{"monk", 0, false},
+		{"ẞẞẞ", 0, false},
+		{"İ", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseWeekday(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseWeekday(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompileWindows(t *testing.T) {
+	got := compileWindows([]DNDWindow{
+		{From: "22:00", To: "07:30", Days: []string{"fri", "Saturday", "ẞ", "nope"}},
+		{From: "25:00", To: "07:00"},
+		{From: "09:00", To: "later"},
+	})
+	if len(got) != 1 {
+		t.Fatalf("got %d windows, want 1: %+v", len(got), got)
+	}
+	w := got[0]
+	if w.from != 22*60 || w.to != 7*60+30 {
+		t.Errorf("from, to = %d, %d", w.from, w.to)
+	}
+	if len(w.days) != 2 || w.days[0] != time.Friday || w.days[1] != time.Saturday {
+		t.Errorf("days = %v", w.days)
+	}
+}
+
+func TestWindowActive(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+	}
+	overnight := compiledWindow{from: 22 * 60, to: 7 * 60}
+	fridayNight := compiledWindow{from: 22 * 60, to: 7 * 60, days: []time.Weekday{time.Friday}}
+	office := compiledWindow{from: 9 * 60, to: 17 * 60}
+
+	tests := []struct {
+		name   string
+		w      compiledWindow
+		now    time.Time
+		active bool
+		end    time.Time
+	}{
+		{"before the start", overnight, at(16, 21, 59), false, time.Time{}},
+		{"at the start", overnight, at(16, 22, 0), true, at(17, 7, 0)},
+		{"past midnight", overnight, at(17, 3, 0), true, at(17, 7, 0)},
+		{"at the end", overnight, at(17, 7, 0), false, time.Time{}},
+		{"started on its day", fridayNight, at(16, 23, 0), true, at(17, 7, 0)},
+		{"carries into saturday", fridayNight, at(17, 6, 59), true, at(17, 7, 0)},
+		{"not on saturday night", fridayNight, at(17, 23, 0), false, time.Time{}},
+		{"not thursday's morning", fridayNight, at(16, 3, 0), false, time.Time{}},
+		{"inside a day window", office, at(16, 12, 0), true, at(16, 17, 0)},
+		{"after a day window", office, at(16, 17, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		active, end := tt.w.active(tt.now)
+		if active != tt.active || !end.Equal(tt.end) {
+			t.Errorf("%s: active(%v) = %v, %v; want %v, %v", tt.name, tt.now, active, end, tt.active, tt.end)
+		}
+	}
+}
+
+func TestSnapshotLeavesOutHeldBack(t *testing.T) {
+	activeNotifications.mu.Lock()
+	activeNotifications.data = map[uint32]subscribe.Notification{1: {ID: 1}, 2: {ID: 2}, 3: {ID: 3}}
+	activeNotifications.mu.Unlock()
+	t.Cleanup(func() {
+		activeNotifications.mu.Lock()
+		activeNotifications.data = make(map[uint32]subscribe.Notification)
+		activeNotifications.mu.Unlock()
+		heldBack.mu.Lock()
+		heldBack.items = nil
+		heldBack.mu.Unlock()
+	})
+
+	ids := func() []uint32 {
+		activeNotifications.mu.Lock()
+		defer activeNotifications.mu.Unlock()
+		var out []uint32
+		for _, n := range getSortedSnapshot() {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+
+	holdBack(subscribe.Notification{ID: 1})
+	holdBack(subscribe.Notification{ID: 2})
+	holdBack(subscribe.Notification{ID: 2}) // an update of a held one
+	if got := ids(); !slices.Equal(got, []uint32{3}) {
+		t.Errorf("snapshot during DND = %v, want [3]", got)
+	}
+	if n := len(heldIDs()); n != 2 {
+		t.Errorf("%d held back, want 2", n)
+	}
+
+	release(2)
+	if got := ids(); !slices.Equal(got, []uint32{3, 2}) {
+		t.Errorf("snapshot after releasing 2 = %v, want [3 2]", got)
+	}
+}
+
+func TestEndDNDSummarizesOnlyOpenNotifications(t *testing.T) {
+	rec := sink.NewRecorder()
+	sink.Default.Set(rec)
+	seedNotificationID.Do(func() {}) // leave the real history alone
+	events := bus.Subscribe([]string{"NOTIFICATION_CLOSED", "NOTIFICATION_DND_SUMMARY"})
+	t.Cleanup(func() {
+		events.Close()
+		sink.Default.Set(sink.NewEww(0))
+		activeNotifications.mu.Lock()
+		for id := range activeNotifications.data {
+			cancelExpiry(id)
+		}
+		activeNotifications.data = make(map[uint32]subscribe.Notification)
+		activeNotifications.mu.Unlock()
+	})
+
+	activeNotifications.mu.Lock()
+	for id := uint32(1); id <= 3; id++ {
+		activeNotifications.data[id] = subscribe.Notification{ID: id, AppName: "chat"}
+	}
+	activeNotifications.mu.Unlock()
+	for id := uint32(1); id <= 3; id++ {
+		holdBack(subscribe.Notification{ID: id, AppName: "chat"})
+	}
+
+	closeActive(2, closeByCall) // the app withdrew it during DND
+	if ids := heldIDs(); len(ids) != 2 || ids[2] {
+		t.Fatalf("held back %v, want 1 and 3", ids)
+	}
+
+	endDND()
+
+	var closed []notificationClosedEvent
+	var summary subscribe.Notification
+	for len(events.C) > 0 {
+		e := <-events.C
+		switch d := e.Data.(type) {
+		case notificationClosedEvent:
+			closed = append(closed, d)
+		case subscribe.Notification:
+			summary = d
+		}
+	}
+	want := []notificationClosedEvent{{2, closeByCall}, {1, closeUndefined}, {3, closeUndefined}}
+	if !slices.Equal(closed, want) {
+		t.Errorf("closed %v, want %v", closed, want)
+	}
+	if summary.Summary != "2 notifications while Do Not Disturb was on" || summary.Body != "2 from chat" {
+		t.Errorf("summary %q: %q", summary.Summary, summary.Body)
+	}
+
+	activeNotifications.mu.Lock()
+	_, ok := activeNotifications.data[summary.ID]
+	n := len(activeNotifications.data)
+	activeNotifications.mu.Unlock()
+	if !ok || n != 1 {
+		t.Errorf("%d active after DND, want only the summary", n)
+	}
+}
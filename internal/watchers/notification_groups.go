@@ -0,0 +1,119 @@
+package watchers
+
+import (
+	"sync"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+// NotificationGroup stacks the notifications of one application. It only
+// carries their IDs, newest first; widgets take the notifications from
+// NOTIFICATION and NOTIFICATION_HISTORY, so they are not published twice.
+type NotificationGroup struct {
+	Key     string   `json:"key"`
+	AppName string   `json:"app_name"`
+	AppIcon string   `json:"app_icon"`
+	Count   int      `json:"count"`
+	Latest  uint32   `json:"latest"`
+	IDs     []uint32 `json:"ids"`
+}
+
+type notificationGroups struct {
+	Active  []NotificationGroup `json:"active"`
+	History []NotificationGroup `json:"history"`
+}
+
+// lastLists remembers what was last published as NOTIFICATION and
+// NOTIFICATION_HISTORY, so NOTIFICATION_GROUPS can be rebuilt from either.
+var lastLists = struct {
+	mu      sync.Mutex
+	active  []subscribe.Notification
+	history []subscribe.Notification
+}{}
+
+// groupKey decides which group n belongs to, following notifications.group_by:
+// "app" (default), "category" to also split by the category hint, or
+// "synchronous" to also split by x-canonical-private-synchronous.
+func groupKey(n subscribe.Notification) string {
+	key := n.AppName
+	switch notificationConfig().GroupBy {
+	case "category":
+		if c, ok := n.Hints["category"].Value().(string); ok && c != "" {
+			key += "/" + c
+		}
+	case "synchronous":
+		if s, ok := n.Hints["x-canonical-private-synchronous"].Value().(string); ok && s != "" {
+			key += "/" + s
+		}
+	}
+	return key
+}
+
+// groupNotifications stacks list, which is sorted newest first. Groups keep
+// the order of their newest notification.
+func groupNotifications(list []subscribe.Notification) []NotificationGroup {
+	groups := []NotificationGroup{}
+	index := make(map[string]int)
+
+	for _, n := range list {
+		key := groupKey(n)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(groups)
+			groups = append(groups, NotificationGroup{
+				Key:     key,
+				AppName: n.AppName,
+				AppIcon: n.AppIcon,
+				Latest:  n.ID,
+			})
+			i = len(groups) - 1
+		}
+		groups[i].Count++
+		groups[i].IDs = append(groups[i].IDs, n.ID)
+	}
+	return groups
+}
+
+// groupIDs returns the IDs of every notification of list in group key.
+func groupIDs(list []subscribe.Notification, key string) []uint32 {
+	var ids []uint32
+	for _, n := range list {
+		if groupKey(n) == key {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+// publishActive publishes the popup list and the groups built from it.
+func publishActive(active []subscribe.Notification) {
+	publish("NOTIFICATION", active)
+
+	lastLists.mu.Lock()
+	lastLists.active = active
+	lastLists.mu.Unlock()
+	publishGroups()
+}
+
+// publishHistory publishes the history list and the groups built from it.
+func publishHistory(history []subscribe.Notification) {
+	publish("NOTIFICATION_HISTORY", history)
+	emitHistoryChanged(history)
+	collectImagesLater()
+
+	lastLists.mu.Lock()
+	lastLists.history = history
+	lastLists.mu.Unlock()
+	publishGroups()
+}
+
+func publishGroups() {
+	lastLists.mu.Lock()
+	groups := notificationGroups{
+		Active:  groupNotifications(lastLists.active),
+		History: groupNotifications(lastLists.history),
+	}
+	lastLists.mu.Unlock()
+
+	publish("NOTIFICATION_GROUPS", groups)
+}
@@ -0,0 +1,78 @@
+package watchers
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+func setGroupBy(t *testing.T, by string) {
+	t.Helper()
+	notificationSettings.mu.Lock()
+	prev := notificationSettings.s
+	notificationSettings.s.GroupBy = by
+	notificationSettings.mu.Unlock()
+	t.Cleanup(func() {
+		notificationSettings.mu.Lock()
+		notificationSettings.s = prev
+		notificationSettings.mu.Unlock()
+	})
+}
+
+func TestGroupNotifications(t *testing.T) {
+	setGroupBy(t, "")
+	n := func(id uint32, app, category string) subscribe.Notification {
+		return subscribe.Notification{ID: id, AppName: app, AppIcon: "/icons/" + app + ".png",
+			Summary: "a long body nobody needs twice",
+			Hints:   map[string]dbus.Variant{"category": dbus.MakeVariant(category)}}
+	}
+	// newest first
+	list := []subscribe.Notification{
+		n(5, "chat", "im.received"), n(4, "mail", "email.arrived"), n(3, "chat", "im.error"),
+		n(2, "chat", "im.received"), n(1, "mail", "email.arrived"),
+	}
+
+	groups := groupNotifications(list)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups: %+v", len(groups), groups)
+	}
+	chat, mail := groups[0], groups[1]
+	if chat.Key != "chat" || chat.Count != 3 || chat.Latest != 5 || !slices.Equal(chat.IDs, []uint32{5, 3, 2}) ||
+		chat.AppIcon != "/icons/chat.png" {
+		t.Errorf("chat group %+v", chat)
+	}
+	if mail.Key != "mail" || mail.Count != 2 || mail.Latest != 4 || !slices.Equal(mail.IDs, []uint32{4, 1}) {
+		t.Errorf("mail group %+v", mail)
+	}
+
+	// Only IDs are published, not the notifications again
+	data, _ := json.Marshal(groups)
+	var raw []map[string]any
+	json.Unmarshal(data, &raw)
+	for _, g := range raw {
+		for key := range g {
+			if !slices.Contains([]string{"key", "app_name", "app_icon", "count", "latest", "ids"}, key) {
+				t.Errorf("group carries %q", key)
+			}
+		}
+	}
+
+	setGroupBy(t, "category")
+	var keys []string
+	for _, g := range groupNotifications(list) {
+		keys = append(keys, g.Key)
+	}
+	if !slices.Equal(keys, []string{"chat/im.received", "mail/email.arrived", "chat/im.error"}) {
+		t.Errorf("keys by category = %q", keys)
+	}
+	if ids := groupIDs(list, "chat/im.received"); !slices.Equal(ids, []uint32{5, 2}) {
+		t.Errorf("groupIDs = %v", ids)
+	}
+
+	if groups := groupNotifications(nil); groups == nil || len(groups) != 0 {
+		t.Errorf("no notifications gave %#v, want an empty list", groups)
+	}
+}
@@ -0,0 +1,299 @@
+package watchers
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+	"github.com/hoppxi/wigo/internal/utils"
+)
+
+// HistorySettings is the `notifications.history` section of wigo.yaml.
+type HistorySettings struct {
+	// MaxEntries caps the notifications kept, 1000 by default. A negative
+	// value keeps all of them.
+	MaxEntries int `mapstructure:"max_entries"`
+	// MaxAge drops notifications older than this; 0 keeps them forever.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+const defaultHistoryEntries = 1000
+
+// HistoryQuery filters the history. Zero fields match everything.
+type HistoryQuery struct {
+	// App is a shell glob on the app name
+	App   string
+	Since time.Time
+	// Grep matches the summary or the body
+	Grep  *regexp.Regexp
+	Limit int
+}
+
+// historyStore keeps the history in memory, newest first, backed by a JSONL
+// file. New notifications are appended to the file; a line for an ID seen
+// before replaces it. Anything else rewrites the file, which also happens
+// once too many appended lines are stale.
+type historyStore struct {
+	mu      sync.Mutex
+	path    string
+	entries []subscribe.Notification
+	index   map[uint32]int // ID -> position in entries
+	lines   int            // lines in the file
+	stamp   fileStamp      // the file as we last read or wrote it
+}
+
+// fileStamp notices when another process (a CLI command) changed the file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	ok      bool
+}
+
+var notificationHistory = &historyStore{path: historyPath}
+
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime(), ok: true}
+}
+
+// sync reloads the file if it changed behind our back. Callers hold mu.
+func (s *historyStore) sync() {
+	if s.index != nil && stampOf(s.path) == s.stamp {
+		return
+	}
+
+	s.entries, s.index, s.lines = nil, map[uint32]int{}, 0
+	data, err := os.ReadFile(s.path)
+	if err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			s.lines++
+
+			var n subscribe.Notification
+			if json.Unmarshal(line, &n) != nil {
+				continue
+			}
+			n.AppIcon = iconFile(n.AppIcon, imageMaxSize())
+			if i, ok := s.index[n.ID]; ok {
+				s.entries[i] = n
+				continue
+			}
+			s.index[n.ID] = len(s.entries)
+			s.entries = append(s.entries, n)
+		}
+	}
+	s.stamp = stampOf(s.path)
+
+	slices.SortFunc(s.entries, func(a, b subscribe.Notification) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	s.reindex()
+	if s.prune(time.Now()) || s.lines > len(s.entries) {
+		s.rewrite()
+	}
+}
+
+func (s *historyStore) reindex() {
+	s.index = make(map[uint32]int, len(s.entries))
+	for i, n := range s.entries {
+		s.index[n.ID] = i
+	}
+}
+
+// prune drops what is past notifications.history limits and reports whether
+// anything went.
+func (s *historyStore) prune(now time.Time) bool {
+	limits := notificationConfig().History
+	before := len(s.entries)
+
+	if limits.MaxAge > 0 {
+		cutoff := now.Add(-limits.MaxAge).Unix()
+		s.entries = slices.DeleteFunc(s.entries, func(n subscribe.Notification) bool {
+			return n.Timestamp < cutoff
+		})
+	}
+
+	maxEntries := limits.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultHistoryEntries
+	}
+	if maxEntries > 0 && len(s.entries) > maxEntries {
+		s.entries = s.entries[:maxEntries]
+	}
+
+	if len(s.entries) == before {
+		return false
+	}
+	s.reindex()
+	return true
+}
+
+// rewrite replaces the file with the entries, oldest first, atomically.
+func (s *historyStore) rewrite() {
+	var buf bytes.Buffer
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		data, _ := json.Marshal(s.entries[i])
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if len(s.entries) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			log.Println("history write error:", err)
+		}
+	} else if err := utils.WriteFileAtomic(s.path, buf.Bytes()); err != nil {
+		log.Println("history write error:", err)
+		return
+	}
+	s.lines = len(s.entries)
+	s.stamp = stampOf(s.path)
+}
+
+func (s *historyStore) appendLine(n subscribe.Notification) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		log.Println("failed to create history directory:", err)
+		return
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Println("history write error:", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(n)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Println("history write error:", err)
+		return
+	}
+	s.lines++
+	s.stamp = stampOf(s.path)
+}
+
+// Add records n, replacing the entry with its ID if there is one.
+func (s *historyStore) Add(n subscribe.Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+
+	if i, ok := s.index[n.ID]; ok {
+		s.entries[i] = n
+	} else {
+		at, _ := slices.BinarySearchFunc(s.entries, n.ID, func(e subscribe.Notification, id uint32) int {
+			return cmp.Compare(id, e.ID)
+		})
+		s.entries = slices.Insert(s.entries, at, n)
+		s.reindex()
+	}
+
+	if s.prune(time.Now()) || s.lines >= 2*max(len(s.entries), 64) {
+		s.rewrite()
+		return
+	}
+	s.appendLine(n)
+}
+
+// RemoveIf drops every notification del matches and returns how many.
+func (s *historyStore) RemoveIf(del func(subscribe.Notification) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+
+	before := len(s.entries)
+	s.entries = slices.DeleteFunc(s.entries, del)
+	removed := before - len(s.entries)
+	if removed > 0 {
+		s.reindex()
+		s.rewrite()
+	}
+	return removed
+}
+
+func (s *historyStore) Remove(id uint32) bool {
+	return s.RemoveIf(func(n subscribe.Notification) bool { return n.ID == id }) > 0
+}
+
+func (s *historyStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.entries, s.index, s.lines, s.stamp = nil, map[uint32]int{}, 0, fileStamp{}
+	return nil
+}
+
+// List returns the history, newest first.
+func (s *historyStore) List() []subscribe.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+	return slices.Clone(s.entries)
+}
+
+func (s *historyStore) Get(id uint32) (subscribe.Notification, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+
+	i, ok := s.index[id]
+	if !ok {
+		return subscribe.Notification{}, false
+	}
+	return s.entries[i], true
+}
+
+func (s *historyStore) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+	return len(s.entries)
+}
+
+// Query returns the notifications matching q, newest first.
+func (s *historyStore) Query(q HistoryQuery) []subscribe.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sync()
+
+	out := []subscribe.Notification{}
+	for _, n := range s.entries {
+		if !q.Since.IsZero() && n.Timestamp < q.Since.Unix() {
+			continue
+		}
+		if q.App != "" {
+			if ok, _ := path.Match(strings.ToLower(q.App), strings.ToLower(n.AppName)); !ok {
+				continue
+			}
+		}
+		if q.Grep != nil && !q.Grep.MatchString(n.Summary) && !q.Grep.MatchString(n.Body) {
+			continue
+		}
+		out = append(out, n)
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,285 @@
+package watchers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+	"github.com/hoppxi/wigo/internal/utils"
+)
+
+// NotificationHook forwards matching notifications to a file, a FIFO or a
+// local HTTP endpoint, rendered through a Go template. Hooks run
+// concurrently, each under its timeout.
+type NotificationHook struct {
+	Name              string `mapstructure:"name"`
+	NotificationMatch `mapstructure:",squash"`
+
+	// Template is a text/template executed with the notification; the
+	// notification JSON when empty. A newline is added if missing.
+	Template string `mapstructure:"template"`
+
+	// File is written with each notification, or appended to with Append.
+	File   string `mapstructure:"file"`
+	Append bool   `mapstructure:"append"`
+	// FIFO is a named pipe, created if missing. Notifications are dropped
+	// while nothing reads it.
+	FIFO string `mapstructure:"fifo"`
+	// URL is POSTed to, with Headers and ContentType, text/plain by
+	// default or application/json without a template. It has to be on
+	// localhost unless AllowRemote is set, since notifications carry
+	// private messages.
+	URL         string            `mapstructure:"url"`
+	Headers     map[string]string `mapstructure:"headers"`
+	ContentType string            `mapstructure:"content_type"`
+	AllowRemote bool              `mapstructure:"allow_remote"`
+
+	// Timeout bounds one run of the hook, 5s by default.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type compiledHook struct {
+	NotificationHook
+	match    compiledMatch
+	template *template.Template
+}
+
+const defaultHookTimeout = 5 * time.Second
+
+// hookFuncs are available in hook templates.
+var hookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"hint": func(n subscribe.Notification, key string) string {
+		if v, ok := n.Hints[key]; ok {
+			return fmt.Sprint(v.Value())
+		}
+		return ""
+	},
+	"urgency": func(n subscribe.Notification) string {
+		u, ok := n.Hints["urgency"].Value().(byte)
+		if !ok {
+			u = 1
+		}
+		return []string{"low", "normal", "critical"}[min(u, 2)]
+	},
+	"time": func(unix int64, layout string) string {
+		return time.Unix(unix, 0).Format(layout)
+	},
+}
+
+func compileHook(h NotificationHook) (compiledHook, error) {
+	c := compiledHook{NotificationHook: h}
+	var err error
+
+	outputs := 0
+	for _, out := range []string{h.File, h.FIFO, h.URL} {
+		if out != "" {
+			outputs++
+		}
+	}
+	if outputs != 1 {
+		return c, errors.New("needs exactly one of file, fifo and url")
+	}
+
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return c, err
+		}
+		if err := h.checkURL(u); err != nil {
+			return c, err
+		}
+	}
+
+	if c.match, err = compileMatch(h.NotificationMatch); err != nil {
+		return c, err
+	}
+	if h.Template != "" {
+		if c.template, err = template.New(h.Name).Funcs(hookFuncs).Parse(h.Template); err != nil {
+			return c, err
+		}
+	}
+	c.File = expandHome(h.File)
+	c.FIFO = expandHome(h.FIFO)
+	if c.Timeout <= 0 {
+		c.Timeout = defaultHookTimeout
+	}
+	return c, nil
+}
+
+// runHooks hands n to every matching hook, each in its own goroutine.
+func runHooks(n subscribe.Notification, urgency byte) {
+	notificationSettings.mu.RLock()
+	hooks := notificationSettings.hooks
+	notificationSettings.mu.RUnlock()
+
+	for _, h := range hooks {
+		if !h.match.matches(n, urgency) {
+			continue
+		}
+		go func() {
+			if err := h.run(n); err != nil {
+				log.Printf("notification hook %s: %v", h.Name, err)
+			}
+		}()
+	}
+}
+
+func (h compiledHook) run(n subscribe.Notification) error {
+	data, err := h.render(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	switch {
+	case h.File != "":
+		return h.writeFile(data)
+	case h.FIFO != "":
+		return h.writeFIFO(ctx, data)
+	default:
+		return h.post(ctx, data)
+	}
+}
+
+func (h compiledHook) render(n subscribe.Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	if h.template == nil {
+		if err := json.NewEncoder(&buf).Encode(n); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if err := h.template.Execute(&buf, n); err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (h compiledHook) writeFile(data []byte) error {
+	if !h.Append {
+		return utils.WriteFileAtomic(h.File, data)
+	}
+
+	f, err := os.OpenFile(h.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFIFO writes without blocking on a FIFO nobody reads; a reader that
+// stops reading runs into the timeout.
+func (h compiledHook) writeFIFO(ctx context.Context, data []byte) error {
+	if _, err := os.Stat(h.FIFO); errors.Is(err, os.ErrNotExist) {
+		if err := syscall.Mkfifo(h.FIFO, 0600); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(h.FIFO, os.O_WRONLY|os.O_APPEND|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return nil // no reader
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		f.SetWriteDeadline(deadline)
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (h compiledHook) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	contentType := h.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+		if h.template == nil {
+			contentType = "application/json"
+		}
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	// A local endpoint must not redirect the notification off the machine
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return h.checkURL(req.URL)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", h.URL, resp.Status)
+	}
+	return nil
+}
+
+// checkURL accepts http and https URLs, on a loopback host unless
+// AllowRemote is set. Host names other than localhost are not resolved, so
+// they count as remote.
+func (h NotificationHook) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url %s is not http or https", u.Redacted())
+	}
+	if h.AllowRemote {
+		return nil
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("url %s is not on this machine, set allow_remote to post to it", u.Redacted())
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(os.Getenv("HOME"), rest)
+	}
+	return path
+}
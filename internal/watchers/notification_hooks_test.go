@@ -0,0 +1,68 @@
+package watchers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+func TestCompileHookURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		remote bool
+		ok     bool
+	}{
+		{"http://localhost:8080/notify", false, true},
+		{"http://127.0.0.1/notify", false, true},
+		{"http://127.1.2.3/notify", false, true},
+		{"https://[::1]:8443/notify", false, true},
+		{"https://example.com/notify", false, false},
+		{"http://192.168.1.10/notify", false, false},
+		{"http://localhost.example.com/notify", false, false},
+		{"https://example.com/notify", true, true},
+		{"ftp://localhost/notify", false, false},
+		{"ftp://example.com/notify", true, false},
+		{"://bad", false, false},
+	}
+	for _, tt := range tests {
+		_, err := compileHook(NotificationHook{Name: "post", URL: tt.url, AllowRemote: tt.remote})
+		if (err == nil) != tt.ok {
+			t.Errorf("compileHook(%q, allow_remote %v) = %v, want ok %v", tt.url, tt.remote, err, tt.ok)
+		}
+	}
+}
+
+func TestHookPost(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "http://192.0.2.1/notify", http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got <- r.Header.Get("Content-Type") + " " + string(body)
+	}))
+	defer srv.Close()
+
+	h, err := compileHook(NotificationHook{Name: "post", URL: srv.URL + "/notify", Template: "{{.Summary}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.run(subscribe.Notification{Summary: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-got; body != "text/plain; charset=utf-8 hello\n" {
+		t.Errorf("posted %q", body)
+	}
+
+	h.URL = srv.URL + "/moved"
+	err = h.post(context.Background(), []byte("hello\n"))
+	if err == nil || !strings.Contains(err.Error(), "allow_remote") {
+		t.Errorf("redirect off the machine: got %v", err)
+	}
+}
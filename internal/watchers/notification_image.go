@@ -0,0 +1,329 @@
+package watchers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/utils"
+	"github.com/hoppxi/wigo/pkg/icontheme"
+)
+
+// ImageSettings is the `notifications.images` section of wigo.yaml.
+type ImageSettings struct {
+	// MaxSize bounds the width and height of cached images, 256 by default.
+	MaxSize int `mapstructure:"max_size"`
+}
+
+const defaultImageSize = 256
+
+// imageGrace keeps a cached image from being collected before the
+// notification showing it reached history.
+const imageGrace = time.Minute
+
+var imageGC = struct {
+	mu      sync.Mutex
+	pending bool
+}{}
+
+func imageMaxSize() int {
+	if size := notificationConfig().Images.MaxSize; size > 0 {
+		return size
+	}
+	return defaultImageSize
+}
+
+// notificationImage picks the image to show for a notification, in the order
+// the spec gives: image-data, image-path, app_icon and last icon_data, with
+// the app's themed icon when there is none. Image data and image files are
+// scaled into the cache under a hash of their content; icon names resolve
+// to a file of the icon theme.
+func notificationImage(hints map[string]dbus.Variant, appIcon, appName string) (string, error) {
+	size := imageMaxSize()
+
+	for _, key := range []string{"image-data", "image_data"} {
+		if v, ok := hints[key]; ok {
+			return cacheImageData(v, size)
+		}
+	}
+
+	for _, key := range []string{"image-path", "image_path"} {
+		if path, ok := hints[key].Value().(string); ok && path != "" {
+			if file, err := resolveImage(path, size); file != "" || err != nil {
+				return file, err
+			}
+		}
+	}
+
+	if appIcon != "" {
+		if file, err := resolveImage(appIcon, size); file != "" || err != nil {
+			return file, err
+		}
+	}
+
+	if v, ok := hints["icon_data"]; ok {
+		return cacheImageData(v, size)
+	}
+
+	if appName != "" {
+		return icontheme.Lookup(strings.ReplaceAll(strings.ToLower(appName), " ", "-"), size), nil
+	}
+	return "", nil
+}
+
+// resolveImage turns an image-path or app_icon, a file URI, a path or an
+// icon name, into a file; "" for names the theme lacks.
+func resolveImage(path string, size int) (string, error) {
+	path = uriPath(path)
+	if !filepath.IsAbs(path) {
+		return icontheme.Lookup(path, size), nil
+	}
+	return cacheImageFile(path, size)
+}
+
+// iconFile turns an icon, a path, a file URI or a name, into a file to
+// show without caching it; "" for names the theme lacks. Icons of the
+// history, written before names were resolved, go through it too.
+func iconFile(icon string, size int) string {
+	icon = uriPath(icon)
+	if icon == "" || filepath.IsAbs(icon) {
+		return icon
+	}
+	return icontheme.Lookup(icon, size)
+}
+
+// uriPath returns the path of a file URI, other values as they are.
+func uriPath(s string) string {
+	rest, ok := strings.CutPrefix(s, "file://")
+	if !ok {
+		return s
+	}
+	if unescaped, err := url.PathUnescape(rest); err == nil {
+		return unescaped
+	}
+	return rest
+}
+
+// cacheImageFile copies an image file into the cache, scaled down to size,
+// since apps often remove the file once the notification was sent. Files
+// that cannot be decoded, like SVGs, are used in place.
+func cacheImageFile(path string, size int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return path, nil
+	}
+
+	name := imageHash(size, data)
+	if file, ok := cachedImage(name); ok {
+		return file, nil
+	}
+	if cfg.Width <= size && cfg.Height <= size {
+		ext := ".png"
+		if format == "jpeg" {
+			ext = ".jpg"
+		}
+		file := filepath.Join(cacheDir, name+ext)
+		return file, utils.WriteFileAtomic(file, data)
+	}
+	return scaleImage(path, cfg.Width, cfg.Height, size, name)
+}
+
+// cacheImageData writes raw image data to the cache as a PNG no larger than
+// size.
+func cacheImageData(v dbus.Variant, size int) (string, error) {
+	img, err := decodeImageData(v)
+	if err != nil {
+		return "", err
+	}
+
+	b := img.Bounds()
+	header := binary.LittleEndian.AppendUint32(nil, uint32(b.Dx()))
+	name := imageHash(size, header, img.Pix)
+	if file, ok := cachedImage(name); ok {
+		return file, nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	if b.Dx() <= size && b.Dy() <= size {
+		file := filepath.Join(cacheDir, name+".png")
+		return file, utils.WriteFileAtomic(file, buf.Bytes())
+	}
+
+	src := filepath.Join(cacheDir, "."+name+".src.png")
+	if err := utils.WriteFileAtomic(src, buf.Bytes()); err != nil {
+		return "", err
+	}
+	defer os.Remove(src)
+	return scaleImage(src, b.Dx(), b.Dy(), size, name)
+}
+
+// scaleImage scales src to fit in size, keeping its aspect, and stores it
+// in the cache as name.
+func scaleImage(src string, width, height, size int, name string) (string, error) {
+	scale := float64(size) / float64(max(width, height))
+	w := max(1, int(math.Round(float64(width)*scale)))
+	h := max(1, int(math.Round(float64(height)*scale)))
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := utils.ObjectFitCover(src, w, h, cacheDir, "."+name+".tmp")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(cacheDir, name+filepath.Ext(tmp))
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return file, nil
+}
+
+// imageHash names a cache entry after the image and the size it was
+// scaled to.
+func imageHash(size int, parts ...[]byte) string {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, uint32(size))
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// cachedImage returns the cache file of name if it exists, marking it as
+// used.
+func cachedImage(name string) (string, bool) {
+	for _, ext := range []string{".png", ".jpg"} {
+		file := filepath.Join(cacheDir, name+ext)
+		if _, err := os.Stat(file); err == nil {
+			now := time.Now()
+			os.Chtimes(file, now, now)
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// collectImagesLater runs collectImages once the grace period passed,
+// batching history changes that come together.
+func collectImagesLater() {
+	imageGC.mu.Lock()
+	defer imageGC.mu.Unlock()
+	if imageGC.pending {
+		return
+	}
+	imageGC.pending = true
+	time.AfterFunc(imageGrace, func() {
+		imageGC.mu.Lock()
+		imageGC.pending = false
+		imageGC.mu.Unlock()
+		collectImages()
+	})
+}
+
+// collectImages removes cached images no notification in history or on
+// screen refers to, so the cache follows history retention.
+func collectImages() {
+	keep := map[string]bool{}
+	for _, n := range notificationHistory.List() {
+		keep[n.AppIcon] = true
+	}
+	activeNotifications.mu.Lock()
+	for _, n := range activeNotifications.data {
+		keep[n.AppIcon] = true
+	}
+	activeNotifications.mu.Unlock()
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-imageGrace)
+	removed := 0
+	for _, e := range entries {
+		file := filepath.Join(cacheDir, e.Name())
+		if e.IsDir() || keep[file] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(file) == nil {
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("Removed %d unused notification images", removed)
+	}
+}
+
+// decodeImageData decodes the (iiibiiay) image structure. Rows are
+// rowstride bytes apart; the last one may come without its padding.
+func decodeImageData(v dbus.Variant) (*image.NRGBA, error) {
+	s, ok := v.Value().([]any)
+	if !ok || len(s) != 7 {
+		return nil, errors.New("image data is not (iiibiiay)")
+	}
+
+	width, ok1 := s[0].(int32)
+	height, ok2 := s[1].(int32)
+	rowstride, ok3 := s[2].(int32)
+	hasAlpha, ok4 := s[3].(bool)
+	bitsPerSample, ok5 := s[4].(int32)
+	channels, ok6 := s[5].(int32)
+	data, ok7 := s[6].([]byte)
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6 && ok7) {
+		return nil, errors.New("image data is not (iiibiiay)")
+	}
+
+	if bitsPerSample != 8 {
+		return nil, fmt.Errorf("unsupported image data: %d bits per sample", bitsPerSample)
+	}
+	if (channels != 3 && channels != 4) || (hasAlpha && channels != 4) {
+		return nil, fmt.Errorf("unsupported image data: %d channels, alpha %v", channels, hasAlpha)
+	}
+	if width <= 0 || height <= 0 || rowstride < width*channels {
+		return nil, fmt.Errorf("invalid image data: %dx%d, rowstride %d", width, height, rowstride)
+	}
+
+	w, h, stride, ch := int(width), int(height), int(rowstride), int(channels)
+	if len(data) < stride*(h-1)+w*ch {
+		return nil, fmt.Errorf("image data too short: %d bytes for %dx%d", len(data), w, h)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		row := data[y*stride:]
+		for x := range w {
+			p := row[x*ch:]
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = p[0], p[1], p[2], 255
+			if hasAlpha {
+				img.Pix[i+3] = p[3]
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,47 @@
+package watchers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hoppxi/wigo/pkg/icontheme"
+)
+
+func TestIconFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(home, "share"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	icon := filepath.Join(home, ".icons", "notifications.png")
+	os.MkdirAll(filepath.Dir(icon), 0755)
+	os.WriteFile(icon, nil, 0644)
+	icontheme.Default().Flush() // other tests may have looked icons up already
+
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"notifications", icon},
+		{"no-such-icon", ""},
+		{"/tmp/some image.png", "/tmp/some image.png"},
+		{"file:///tmp/some%20image.png", "/tmp/some image.png"},
+	}
+	for _, tt := range tests {
+		if got := iconFile(tt.in, 48); got != tt.want {
+			t.Errorf("iconFile(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// History written before names were resolved
+	path := filepath.Join(home, "history.jsonl")
+	line := fmt.Sprintf(`{"id":1,"app_name":"wigo","app_icon":"notifications","summary":"old","timestamp":%d}`+"\n", time.Now().Unix())
+	os.WriteFile(path, []byte(line), 0644)
+	store := &historyStore{path: path}
+	if got := store.List(); len(got) != 1 || got[0].AppIcon != icon {
+		t.Errorf("history = %+v, want app_icon %q", got, icon)
+	}
+}
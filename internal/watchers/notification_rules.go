@@ -0,0 +1,190 @@
+package watchers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+)
+
+// NotificationMatch selects notifications. Every field that is set must
+// match.
+type NotificationMatch struct {
+	// AppName and Category are shell globs, Summary and Body are regular
+	// expressions, Urgency is low, normal or critical.
+	AppName  string `mapstructure:"app_name"`
+	Summary  string `mapstructure:"summary"`
+	Body     string `mapstructure:"body"`
+	Category string `mapstructure:"category"`
+	Urgency  string `mapstructure:"urgency"`
+}
+
+// NotificationRule matches incoming notifications and changes how they are
+// handled. Rules are applied in order and later rules override earlier ones.
+type NotificationRule struct {
+	Name              string `mapstructure:"name"`
+	NotificationMatch `mapstructure:",squash"`
+
+	// Drop discards the notification entirely.
+	Drop bool `mapstructure:"drop"`
+	// HidePopup keeps it in history without showing a popup.
+	HidePopup bool `mapstructure:"hide_popup"`
+	// SkipHistory shows the popup without recording it.
+	SkipHistory bool `mapstructure:"skip_history"`
+	// Timeout overrides the expire timeout, 0 means never expire.
+	Timeout *time.Duration `mapstructure:"timeout"`
+	// SetUrgency forces the urgency: low, normal or critical.
+	SetUrgency string `mapstructure:"set_urgency"`
+	// Exec runs through sh -c with the notification JSON in $NOTIFICATION.
+	Exec string `mapstructure:"exec"`
+}
+
+type compiledMatch struct {
+	NotificationMatch
+	summary *regexp.Regexp
+	body    *regexp.Regexp
+	urgency int
+}
+
+type compiledRule struct {
+	NotificationRule
+	match compiledMatch
+	force int
+}
+
+// ruleResult is what the matching rules decided for one notification.
+type ruleResult struct {
+	drop        bool
+	hidePopup   bool
+	skipHistory bool
+	timeout     *time.Duration
+	urgency     int
+	exec        []string
+}
+
+var urgencyNames = map[string]byte{"low": 0, "normal": 1, "critical": 2}
+
+func parseUrgency(s string) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+	u, ok := urgencyNames[s]
+	if !ok {
+		return -1, fmt.Errorf("unknown urgency %q (use low, normal or critical)", s)
+	}
+	return int(u), nil
+}
+
+func compileMatch(m NotificationMatch) (compiledMatch, error) {
+	c := compiledMatch{NotificationMatch: m}
+	var err error
+
+	if m.AppName != "" {
+		if _, err := path.Match(m.AppName, ""); err != nil {
+			return c, fmt.Errorf("app_name: %w", err)
+		}
+	}
+	if m.Category != "" {
+		if _, err := path.Match(m.Category, ""); err != nil {
+			return c, fmt.Errorf("category: %w", err)
+		}
+	}
+	if m.Summary != "" {
+		if c.summary, err = regexp.Compile(m.Summary); err != nil {
+			return c, fmt.Errorf("summary: %w", err)
+		}
+	}
+	if m.Body != "" {
+		if c.body, err = regexp.Compile(m.Body); err != nil {
+			return c, fmt.Errorf("body: %w", err)
+		}
+	}
+	if c.urgency, err = parseUrgency(m.Urgency); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func compileRule(r NotificationRule) (compiledRule, error) {
+	c := compiledRule{NotificationRule: r}
+	var err error
+
+	if c.match, err = compileMatch(r.NotificationMatch); err != nil {
+		return c, err
+	}
+	if c.force, err = parseUrgency(r.SetUrgency); err != nil {
+		return c, fmt.Errorf("set_urgency: %w", err)
+	}
+	return c, nil
+}
+
+func (m compiledMatch) matches(n subscribe.Notification, urgency byte) bool {
+	if m.AppName != "" {
+		if ok, _ := path.Match(m.AppName, n.AppName); !ok {
+			return false
+		}
+	}
+	if m.Category != "" {
+		category, _ := n.Hints["category"].Value().(string)
+		if ok, _ := path.Match(m.Category, category); !ok {
+			return false
+		}
+	}
+	if m.summary != nil && !m.summary.MatchString(n.Summary) {
+		return false
+	}
+	if m.body != nil && !m.body.MatchString(n.Body) {
+		return false
+	}
+	if m.urgency >= 0 && byte(m.urgency) != urgency {
+		return false
+	}
+	return true
+}
+
+// applyRules runs every configured rule against n.
+func applyRules(n subscribe.Notification, urgency byte) ruleResult {
+	notificationSettings.mu.RLock()
+	rules := notificationSettings.rules
+	notificationSettings.mu.RUnlock()
+
+	res := ruleResult{urgency: -1}
+	for _, r := range rules {
+		if !r.match.matches(n, urgency) {
+			continue
+		}
+		if r.Drop {
+			res.drop = true
+			return res
+		}
+		res.hidePopup = res.hidePopup || r.HidePopup
+		res.skipHistory = res.skipHistory || r.SkipHistory
+		if r.Timeout != nil {
+			res.timeout = r.Timeout
+		}
+		if r.force >= 0 {
+			res.urgency = r.force
+			urgency = byte(r.force)
+		}
+		if r.Exec != "" {
+			res.exec = append(res.exec, r.Exec)
+		}
+	}
+	return res
+}
+
+func runShell(command string, n subscribe.Notification) {
+	jsonBytes, _ := json.Marshal(n)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "NOTIFICATION="+string(jsonBytes))
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("notification rule exec error: %v", err)
+	}
+}
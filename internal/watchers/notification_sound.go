@@ -0,0 +1,242 @@
+package watchers
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hoppxi/wigo/internal/subscribe"
+	"github.com/hoppxi/wigo/pkg/audioinfo"
+)
+
+// SoundSettings is the `notifications.sound` section of wigo.yaml.
+type SoundSettings struct {
+	// Enabled turns notification sounds on, the default.
+	Enabled *bool `mapstructure:"enabled"`
+	// Theme is the freedesktop sound theme, "freedesktop" by default.
+	Theme string `mapstructure:"theme"`
+	// Volume goes from 0 to 100, 100 by default.
+	Volume *int `mapstructure:"volume"`
+	// Urgency maps low, normal and critical to the sound played when the
+	// notification asks for none. A sound is a theme name or a file.
+	Urgency map[string]string `mapstructure:"urgency"`
+	// Apps overrides Urgency per app name; "none" keeps an app quiet.
+	Apps map[string]string `mapstructure:"apps"`
+}
+
+var (
+	// playing keeps sounds of notifications arriving together from piling
+	// up; while one plays, the others are skipped.
+	playing atomic.Bool
+
+	soundCache = struct {
+		mu     sync.Mutex
+		sounds map[string]*audioinfo.Sound
+	}{sounds: make(map[string]*audioinfo.Sound)}
+)
+
+const maxCachedSounds = 32
+
+// playNotificationSound plays the sound of n, if any: its sound-file or
+// sound-name hint, else the app or urgency default from wigo.yaml.
+func playNotificationSound(n subscribe.Notification, urgency byte) {
+	cfg := notificationConfig().Sound
+	if (cfg.Enabled != nil && !*cfg.Enabled) || n.SuppressSound {
+		return
+	}
+
+	file := n.SoundFile
+	name := n.SoundName
+	if file == "" && name == "" {
+		name = defaultSound(cfg, n.AppName, urgency)
+		if strings.Contains(name, "/") {
+			file, name = name, ""
+		}
+	}
+	if (file == "" && name == "") || name == "none" {
+		return
+	}
+
+	if !playing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer playing.Store(false)
+
+		if file == "" {
+			var err error
+			if file, err = resolveSound(name, cfg.Theme); err != nil {
+				log.Printf("notification sound: %v", err)
+				return
+			}
+			if file == "" {
+				return // disabled in the theme
+			}
+		}
+
+		file = expandHome(strings.TrimPrefix(file, "file://"))
+		sound, err := loadSound(file)
+		if err != nil {
+			log.Printf("notification sound: %v", err)
+			return
+		}
+
+		volume := 100
+		if cfg.Volume != nil {
+			volume = *cfg.Volume
+		}
+		if err := audioinfo.Play(sound, float64(volume)/100); err != nil {
+			log.Printf("notification sound: %v", err)
+		}
+	}()
+}
+
+func defaultSound(cfg SoundSettings, appName string, urgency byte) string {
+	for app, sound := range cfg.Apps {
+		if strings.EqualFold(app, appName) {
+			return sound
+		}
+	}
+	return cfg.Urgency[[]string{"low", "normal", "critical"}[min(urgency, 2)]]
+}
+
+// loadSound decodes a sound file once and keeps it for the next time.
+func loadSound(path string) (*audioinfo.Sound, error) {
+	soundCache.mu.Lock()
+	s, ok := soundCache.sounds[path]
+	soundCache.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := audioinfo.LoadSound(path)
+	if err != nil {
+		return nil, err
+	}
+
+	soundCache.mu.Lock()
+	if len(soundCache.sounds) >= maxCachedSounds {
+		clear(soundCache.sounds)
+	}
+	soundCache.sounds[path] = s
+	soundCache.mu.Unlock()
+	return s, nil
+}
+
+// soundDirs lists the base directories of sound themes, per the XDG base
+// directory spec.
+func soundDirs() []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(os.Getenv("HOME"), ".local/share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	dirs := []string{filepath.Join(dataHome, "sounds")}
+	for _, d := range filepath.SplitList(dataDirs) {
+		dirs = append(dirs, filepath.Join(d, "sounds"))
+	}
+	return dirs
+}
+
+// resolveSound finds the file of a sound name following the freedesktop
+// sound theme spec: the theme and those it inherits from, then
+// "freedesktop", trying less specific names ("message-new-instant",
+// "message-new", "message") in each. It returns "" for a sound the theme
+// disabled.
+func resolveSound(name, theme string) (string, error) {
+	if theme == "" {
+		theme = "freedesktop"
+	}
+	dirs := soundDirs()
+
+	for n := name; n != ""; {
+		seen := map[string]bool{}
+		for _, t := range append(themeChain(dirs, theme, seen), themeChain(dirs, "freedesktop", seen)...) {
+			if file, found := findSound(dirs, t, n); found {
+				return file, nil
+			}
+		}
+
+		i := strings.LastIndex(n, "-")
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return "", errors.New("sound " + name + " not found in theme " + theme)
+}
+
+// themeChain returns theme and the themes it inherits from, in lookup
+// order.
+func themeChain(dirs []string, theme string, seen map[string]bool) []string {
+	if seen[theme] {
+		return nil
+	}
+	seen[theme] = true
+
+	chain := []string{theme}
+	for _, parent := range themeIndex(dirs, theme, "Inherits") {
+		chain = append(chain, themeChain(dirs, parent, seen)...)
+	}
+	return chain
+}
+
+// themeIndex reads a comma separated key of a theme's index.theme.
+func themeIndex(dirs []string, theme, key string) []string {
+	for _, d := range dirs {
+		f, err := os.Open(filepath.Join(d, theme, "index.theme"))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			k, v, ok := strings.Cut(scanner.Text(), "=")
+			if ok && strings.TrimSpace(k) == key {
+				var out []string
+				for _, item := range strings.Split(v, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						out = append(out, item)
+					}
+				}
+				return out
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// findSound looks for name in the stereo directory of theme. A .disabled
+// file turns the sound off, then WAV is tried before Ogg as the spec asks.
+func findSound(dirs []string, theme, name string) (string, bool) {
+	subdirs := themeIndex(dirs, theme, "Directories")
+	if len(subdirs) == 0 {
+		subdirs = []string{"stereo"}
+	}
+
+	for _, d := range dirs {
+		for _, sub := range subdirs {
+			base := filepath.Join(d, theme, sub, name)
+			if _, err := os.Stat(base + ".disabled"); err == nil {
+				return "", true
+			}
+			for _, ext := range []string{".wav", ".oga", ".ogg"} {
+				if _, err := os.Stat(base + ext); err == nil {
+					return base + ext, true
+				}
+			}
+		}
+	}
+	return "", false
+}
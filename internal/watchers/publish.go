@@ -0,0 +1,49 @@
+package watchers
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/sink"
+)
+
+var lastPublished = struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}{times: map[string]time.Time{}}
+
+// publish sends data JSON encoded to every frontend sink.
+func publish(name string, data any) {
+	touch(name)
+	sink.Publish(name, data)
+}
+
+// publishPlain sends data formatted with %v, for booleans, numbers and bare
+// strings.
+func publishPlain(name string, data any) {
+	touch(name)
+	sink.PublishPlain(name, data)
+}
+
+func touch(name string) {
+	lastPublished.mu.Lock()
+	lastPublished.times[name] = time.Now()
+	lastPublished.mu.Unlock()
+}
+
+// LastEvent returns when the watcher last published one of its variables.
+func (s Spec) LastEvent() time.Time {
+	lastPublished.mu.Lock()
+	defer lastPublished.mu.Unlock()
+
+	var last time.Time
+	for name, t := range lastPublished.times {
+		for _, pattern := range s.Vars {
+			if ok, _ := path.Match(pattern, name); ok && t.After(last) {
+				last = t
+			}
+		}
+	}
+	return last
+}
@@ -0,0 +1,182 @@
+package watchers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/jfreymuth/pulse"
+)
+
+// Options tunes one watcher from the `watchers:` section of wigo.yaml.
+type Options struct {
+	Enabled *bool `mapstructure:"enabled"`
+	// Force skips the availability probe.
+	Force bool `mapstructure:"force"`
+	// Interval is the polling/tick period for watchers that poll.
+	Interval time.Duration `mapstructure:"interval"`
+	// OSDTimeout is how long an OSD popup stays visible.
+	OSDTimeout time.Duration `mapstructure:"osd_timeout"`
+}
+
+// Spec describes a watcher the daemon can run.
+type Spec struct {
+	Name string
+	// Start runs until stop is closed. Returning early, with or without an
+	// error, counts as a crash and the supervisor restarts it.
+	Start func(stop <-chan struct{}) error
+	// Vars are the variables the watcher publishes, used to report when it
+	// last produced something.
+	Vars []string
+	// Available returns why the watcher cannot work on this machine.
+	Available func() error
+	// OptIn watchers only run when enabled in wigo.yaml.
+	OptIn bool
+}
+
+var Registry = []Spec{
+	{Name: "audio", Start: StartAudioWatcher, Available: hasPulse,
+		Vars: []string{"AUDIO_INFO", "OSD_VOLUME"}},
+	{Name: "battery", Start: StartBatteryWatcher, Available: hasBattery,
+		Vars: []string{"BATTERY_INFO", "BATTERY_DYNAMIC_INFO", "OSD_BATTERY_*", "OSD_CHARGER_*"}},
+	{Name: "network", Start: StartNetworkWatcher, Available: hasSystemService("org.freedesktop.NetworkManager"),
+		Vars: []string{"NETWORK_INFO"}},
+	{Name: "bluetooth", Start: StartBluetoothWatcher, Available: hasSystemService("org.bluez"),
+		Vars: []string{"BLUETOOTH_INFO"}},
+	{Name: "display", Start: StartDisplayWatcher, Available: hasBacklight,
+		Vars: []string{"DISPLAY_INFO", "OSD_DISPLAY"}},
+	{Name: "media", Start: StartMediaWatcher, Available: hasSessionBus,
+		Vars: []string{"MEDIA_*"}},
+	{Name: "workspace", Start: StartWorkspaceWatcher, Available: hasHyprland,
+		Vars: []string{"ACTIVE_WINDOW", "ACTIVE_WORKSPACE", "WORKSPACES"}},
+	{Name: "misc", Start: StartMiscWatcher,
+		Vars: []string{"MISC_INFO"}},
+	{Name: "esc", Start: StartEscWatcher, Available: hasHyprland},
+	{Name: "leds", Start: StartLEDsWatcher, Available: hasLockLEDs,
+		Vars: []string{"OSD_CAPS", "OSD_NUM", "OSD_SCROLL"}},
+	{Name: "notification", Start: StartNotificationService, Available: hasSessionBus, OptIn: true,
+		Vars: []string{"NOTIFICATION", "NOTIFICATION_HISTORY", "NOTIFICATION_GROUPS", "NOTIFICATION_DND"}},
+}
+
+func Lookup(name string) (Spec, bool) {
+	i := slices.IndexFunc(Registry, func(s Spec) bool { return s.Name == name })
+	if i < 0 {
+		return Spec{}, false
+	}
+	return Registry[i], true
+}
+
+var watcherOptions = struct {
+	mu   sync.RWMutex
+	opts map[string]Options
+}{opts: map[string]Options{}}
+
+// Configure replaces the options of every watcher.
+func Configure(opts map[string]Options) {
+	if opts == nil {
+		opts = map[string]Options{}
+	}
+	watcherOptions.mu.Lock()
+	watcherOptions.opts = opts
+	watcherOptions.mu.Unlock()
+}
+
+func OptionsFor(name string) Options {
+	watcherOptions.mu.RLock()
+	defer watcherOptions.mu.RUnlock()
+	return watcherOptions.opts[name]
+}
+
+// EnabledFor reports whether spec should run with these options.
+func (o Options) EnabledFor(spec Spec) bool {
+	if o.Enabled != nil {
+		return *o.Enabled
+	}
+	return !spec.OptIn
+}
+
+func (o Options) interval(def time.Duration) time.Duration {
+	if o.Interval > 0 {
+		return o.Interval
+	}
+	return def
+}
+
+func (o Options) osdTimeout(def time.Duration) time.Duration {
+	if o.OSDTimeout > 0 {
+		return o.OSDTimeout
+	}
+	return def
+}
+
+func hasPulse() error {
+	c, err := pulse.NewClient()
+	if err != nil {
+		return err
+	}
+	c.Close()
+	return nil
+}
+
+func hasBattery() error {
+	matches, _ := filepath.Glob("/sys/class/power_supply/BAT*")
+	if len(matches) == 0 {
+		return errors.New("no battery found in /sys/class/power_supply")
+	}
+	return nil
+}
+
+func hasBacklight() error {
+	matches, _ := filepath.Glob("/sys/class/backlight/*")
+	if len(matches) == 0 {
+		return errors.New("no backlight found in /sys/class/backlight")
+	}
+	return nil
+}
+
+func hasLockLEDs() error {
+	matches, _ := filepath.Glob("/sys/class/leds/*lock")
+	if len(matches) == 0 {
+		return errors.New("no lock LEDs found in /sys/class/leds")
+	}
+	return nil
+}
+
+func hasHyprland() error {
+	if os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") == "" {
+		return errors.New("not running under Hyprland")
+	}
+	return nil
+}
+
+func hasSessionBus() error {
+	_, err := dbus.SessionBus()
+	return err
+}
+
+// hasSystemService checks that name is running or activatable on the system
+// bus.
+func hasSystemService(name string) func() error {
+	return func() error {
+		conn, err := dbus.SystemBus()
+		if err != nil {
+			return err
+		}
+
+		var owned bool
+		if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&owned); err == nil && owned {
+			return nil
+		}
+
+		var activatable []string
+		if err := conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable); err == nil && slices.Contains(activatable, name) {
+			return nil
+		}
+
+		return errors.New(name + " is not available on the system bus")
+	}
+}
@@ -1,6 +1,7 @@
 package watchers
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hoppxi/wigo/internal/subscribe"
@@ -51,7 +52,7 @@ func updateActiveWindow() {
 		return
 	}
 	lastActiveWindow = aw
-	updateEww("ACTIVE_WINDOW", aw)
+	publish("ACTIVE_WINDOW", aw)
 }
 
 func updateActiveWorkspace() {
@@ -60,7 +61,7 @@ func updateActiveWorkspace() {
 		return
 	}
 	lastActiveWorkspace = aws
-	updateEww("ACTIVE_WORKSPACE", aws)
+	publish("ACTIVE_WORKSPACE", aws)
 }
 
 func updateWorkspaceList() {
@@ -69,16 +70,15 @@ func updateWorkspaceList() {
 		return
 	}
 	lastWorkspaceList = ws
-	updateEww("WORKSPACES", ws)
+	publish("WORKSPACES", ws)
 }
 
-func StartWorkspaceWatcher(stop <-chan struct{}) {
+func StartWorkspaceWatcher(stop <-chan struct{}) error {
 	fmt.Println("[DEBUG] Starting workspace watcher...")
 
-	rawEvents, err := subscribe.SubscribeEvents()
+	rawEvents, err := subscribe.SubscribeEvents(stop)
 	if err != nil {
-		fmt.Println("Cannot subscribe Hyprland events:", err)
-		return
+		return fmt.Errorf("cannot subscribe Hyprland events: %w", err)
 	}
 
 	updateWorkspaceList()
@@ -87,10 +87,10 @@ func StartWorkspaceWatcher(stop <-chan struct{}) {
 	for {
 		select {
 		case <-stop:
-			return
+			return nil
 		case e, ok := <-rawEvents:
 			if !ok {
-				return
+				return errors.New("hyprland event socket closed")
 			}
 
 			switch e.Name {
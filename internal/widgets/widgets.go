@@ -0,0 +1,144 @@
+package widgets
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+const (
+	stateFile    = "/tmp/wigo/wigo_widget_state"
+	closerWidget = "closer"
+)
+
+var (
+	Permanent  = []string{"bar", "wallpaper", "clock", "notification-view", "osd"}
+	Toggleable = []string{"media", "panel", "quick-settings", "power", "launcher"}
+)
+
+func eww(args ...string) error {
+	out, err := exec.Command("eww", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("eww %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func openCloser() {
+	eww("open", closerWidget)
+}
+
+func closeCloser() {
+	eww("close", closerWidget)
+}
+
+func IsPermanent(widget string) bool {
+	return slices.Contains(Permanent, widget)
+}
+
+// Tracked returns the toggleable widget that is currently open, if any.
+func Tracked() string {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Forget clears the tracked widget, for when eww restarted with nothing open.
+func Forget() error {
+	return setTracked("")
+}
+
+func setTracked(widget string) error {
+	if err := os.MkdirAll("/tmp/wigo", 0755); err != nil {
+		return err
+	}
+
+	if widget == "" {
+		f, err := os.OpenFile(stateFile, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	return os.WriteFile(stateFile, []byte(widget), 0644)
+}
+
+func closeWidget(widget string) error {
+	err := eww("close", widget)
+	if !IsPermanent(widget) {
+		setTracked("")
+	}
+
+	closeCloser()
+	return err
+}
+
+// OpenPermanent opens every always-on widget in a single eww call.
+func OpenPermanent() error {
+	return eww(append([]string{"open-many"}, Permanent...)...)
+}
+
+func Open(widget string) error {
+	if !IsPermanent(widget) {
+		if tracked := Tracked(); tracked != "" && tracked != widget {
+			closeWidget(tracked)
+		}
+		setTracked(widget)
+	}
+
+	openCloser()
+	return eww("open", widget)
+}
+
+func Toggle(widget string) error {
+	tracked := Tracked()
+
+	// If toggling OFF the same widget
+	if tracked == widget {
+		err := eww("close", widget)
+		setTracked("")
+		closeCloser()
+		return err
+	}
+
+	if !IsPermanent(widget) {
+		if tracked != "" && tracked != widget {
+			closeWidget(tracked)
+		}
+		setTracked(widget)
+	}
+
+	openCloser()
+	return eww("open", "--toggle", widget)
+}
+
+// Close closes a widget. "all" closes every toggleable widget and keeps the
+// permanent ones, "entire" closes everything.
+func Close(widget string) error {
+	switch widget {
+	case "all":
+		if tracked := Tracked(); tracked != "" {
+			closeWidget(tracked)
+		}
+		for _, w := range Toggleable {
+			eww("close", w)
+		}
+		closeCloser()
+		return nil
+
+	case "entire":
+		for _, w := range append(slices.Clone(Toggleable), Permanent...) {
+			eww("close", w)
+		}
+		setTracked("")
+		closeCloser()
+		return nil
+	}
+
+	return closeWidget(widget)
+}
@@ -56,6 +56,38 @@ in
             type = lib.types.path;
           };
 
+          watchers = lib.mkOption {
+            type = lib.types.attrsOf (lib.types.attrsOf lib.types.anything);
+            default = { };
+            example = {
+              battery.enabled = false;
+              misc.interval = "30s";
+            };
+            description = ''
+              Per-watcher settings keyed by watcher name (audio, battery, network,
+              bluetooth, display, media, workspace, misc, esc, leds, notification).
+              Each entry may set enabled, force, interval and osd_timeout.
+            '';
+          };
+
+          notifications = lib.mkOption {
+            type = lib.types.attrsOf lib.types.anything;
+            default = { };
+            example = {
+              rules = [
+                {
+                  app_name = "Spotify";
+                  hide_popup = true;
+                  skip_history = true;
+                }
+              ];
+            };
+            description = ''
+              Notification daemon settings, written to the `notifications:` section
+              of wigo.yaml. See the README for the available keys.
+            '';
+          };
+
           launcher-ext = lib.mkOption {
             type = lib.types.listOf (
               lib.types.submodule {
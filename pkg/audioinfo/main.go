@@ -3,6 +3,7 @@ package audioinfo
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/jfreymuth/pulse"
 	"github.com/jfreymuth/pulse/proto"
@@ -19,6 +20,39 @@ type AudioInfo struct {
 	Input  AudioDevice `json:"input"`
 }
 
+// shared is the PulseAudio connection reused by every call, opened on first
+// use and again after it broke.
+var shared struct {
+	mu sync.Mutex
+	c  *pulse.Client
+}
+
+// Client returns the shared PulseAudio connection.
+func Client() (*pulse.Client, error) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.c != nil {
+		return shared.c, nil
+	}
+
+	c, err := pulse.NewClient(pulse.ClientApplicationName("wigo"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pulse client: %w", err)
+	}
+	shared.c = c
+	return c, nil
+}
+
+// dropClient closes c after a failed request, so the next call reconnects.
+func dropClient(c *pulse.Client) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.c == c {
+		shared.c.Close()
+		shared.c = nil
+	}
+}
+
 func channelVolumesToPercent(cv proto.ChannelVolumes) int {
 	if len(cv) == 0 {
 		return 100
@@ -79,18 +113,19 @@ func getDeviceInfo(c *pulse.Client, isSink bool) (AudioDevice, error) {
 }
 
 func GetAudioInfo() (*AudioInfo, error) {
-	c, err := pulse.NewClient()
+	c, err := Client()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pulse client: %w", err)
+		return nil, err
 	}
-	defer c.Close()
 
 	out, err := getDeviceInfo(c, true)
 	if err != nil {
+		dropClient(c)
 		return nil, err
 	}
 	in, err := getDeviceInfo(c, false)
 	if err != nil {
+		dropClient(c)
 		return nil, err
 	}
 	info := &AudioInfo{
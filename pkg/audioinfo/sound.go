@@ -0,0 +1,208 @@
+package audioinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/jfreymuth/pulse"
+	"github.com/jfreymuth/pulse/proto"
+)
+
+// Sound is decoded audio: interleaved samples between -1 and 1.
+type Sound struct {
+	Rate     int
+	Channels int
+	Samples  []float32
+}
+
+// LoadSound decodes a WAV or Ogg Vorbis file, told apart by their headers.
+func LoadSound(path string) (*Sound, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if string(magic) != "OggS" {
+		return LoadWAV(path)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return decodeOgg(path, f)
+}
+
+// LoadOgg decodes an Ogg Vorbis file, the format of the freedesktop sound
+// theme.
+func LoadOgg(path string) (*Sound, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeOgg(path, f)
+}
+
+func decodeOgg(path string, r io.Reader) (*Sound, error) {
+	samples, format, err := oggvorbis.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if format.Channels == 0 || format.SampleRate == 0 {
+		return nil, fmt.Errorf("%s: no audio", path)
+	}
+	return &Sound{Rate: format.SampleRate, Channels: format.Channels, Samples: samples}, nil
+}
+
+// LoadWAV decodes a RIFF WAVE file holding 8, 16, 24 or 32 bit PCM or 32 bit
+// float samples.
+func LoadWAV(path string) (*Sound, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%s: not a WAV file", path)
+	}
+
+	var (
+		format, channels, bits uint16
+		rate                   uint32
+		pcm                    []byte
+	)
+	for rest := data[12:]; len(rest) >= 8; {
+		id, size := string(rest[0:4]), int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			size = len(rest)
+		}
+		chunk := rest[:size]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, fmt.Errorf("%s: short fmt chunk", path)
+			}
+			format = binary.LittleEndian.Uint16(chunk[0:2])
+			channels = binary.LittleEndian.Uint16(chunk[2:4])
+			rate = binary.LittleEndian.Uint32(chunk[4:8])
+			bits = binary.LittleEndian.Uint16(chunk[14:16])
+			// WAVE_FORMAT_EXTENSIBLE keeps the real format in its sub format
+			if format == 0xfffe && len(chunk) >= 26 {
+				format = binary.LittleEndian.Uint16(chunk[24:26])
+			}
+		case "data":
+			pcm = chunk
+		}
+
+		// Chunks are padded to an even size
+		rest = rest[min(len(rest), size+size%2):]
+	}
+
+	if channels == 0 || rate == 0 {
+		return nil, fmt.Errorf("%s: missing fmt chunk", path)
+	}
+	if pcm == nil {
+		return nil, fmt.Errorf("%s: missing data chunk", path)
+	}
+
+	s := &Sound{Rate: int(rate), Channels: int(channels)}
+	switch {
+	case format == 1 && bits == 8:
+		for _, b := range pcm {
+			s.Samples = append(s.Samples, (float32(b)-128)/128)
+		}
+	case format == 1 && bits == 16:
+		for i := 0; i+2 <= len(pcm); i += 2 {
+			s.Samples = append(s.Samples, float32(int16(binary.LittleEndian.Uint16(pcm[i:])))/(1<<15))
+		}
+	case format == 1 && bits == 24:
+		for i := 0; i+3 <= len(pcm); i += 3 {
+			v := int32(pcm[i]) | int32(pcm[i+1])<<8 | int32(int8(pcm[i+2]))<<16
+			s.Samples = append(s.Samples, float32(v)/(1<<23))
+		}
+	case format == 1 && bits == 32:
+		for i := 0; i+4 <= len(pcm); i += 4 {
+			s.Samples = append(s.Samples, float32(int32(binary.LittleEndian.Uint32(pcm[i:])))/(1<<31))
+		}
+	case format == 3 && bits == 32:
+		for i := 0; i+4 <= len(pcm); i += 4 {
+			s.Samples = append(s.Samples, math.Float32frombits(binary.LittleEndian.Uint32(pcm[i:])))
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported WAV format %d with %d bits", path, format, bits)
+	}
+	return s, nil
+}
+
+// Play plays s on the default sink at volume (0 to 1) as an event sound
+// and returns once it was played.
+func Play(s *Sound, volume float64) error {
+	if s.Channels < 1 || s.Rate < 1 {
+		return errors.New("invalid sound")
+	}
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+
+	channels := pulse.PlaybackMono
+	frames := downmix(s)
+	if s.Channels >= 2 {
+		channels = pulse.PlaybackStereo
+	}
+
+	gain := float32(max(0, min(volume, 1)))
+	pos := 0
+	r := pulse.Float32Reader(func(buf []float32) (int, error) {
+		n := copy(buf, frames[pos:])
+		for i := range buf[:n] {
+			buf[i] *= gain
+		}
+		pos += n
+		if pos >= len(frames) {
+			return n, pulse.EndOfData
+		}
+		return n, nil
+	})
+
+	stream, err := c.NewPlayback(r, channels, pulse.PlaybackSampleRate(s.Rate),
+		pulse.PlaybackMediaName("wigo notification"),
+		pulse.PlaybackRawOption(func(req *proto.CreatePlaybackStream) {
+			req.Properties["media.role"] = proto.PropListString("event")
+		}))
+	if err != nil {
+		dropClient(c)
+		return err
+	}
+	defer stream.Close()
+
+	stream.Start()
+	// Drain skips streams that already ran out of data, ask the server
+	// directly so short sounds are not cut off
+	if err := c.RawRequest(&proto.DrainPlaybackStream{StreamIndex: stream.StreamIndex()}, nil); err != nil {
+		return err
+	}
+	return stream.Error()
+}
+
+// downmix returns the samples of s with at most two channels.
+func downmix(s *Sound) []float32 {
+	if s.Channels <= 2 {
+		return s.Samples
+	}
+	out := make([]float32, 0, len(s.Samples)/s.Channels*2)
+	for i := 0; i+s.Channels <= len(s.Samples); i += s.Channels {
+		out = append(out, s.Samples[i], s.Samples[i+1])
+	}
+	return out
+}
@@ -28,6 +28,31 @@ func GetIcons() IconsInfo {
 	}
 }
 
+// WithAudio returns a copy of i with the volume icons taken from a.
+func (i IconsInfo) WithAudio(a *audioinfo.AudioInfo) IconsInfo {
+	if a != nil {
+		i.InputVolume = mapInputVolume(a)
+		i.OutputVolume = mapOutputVolume(a)
+	}
+	return i
+}
+
+// WithNetwork returns a copy of i with the network icon taken from n.
+func (i IconsInfo) WithNetwork(n *netinfo.NetworkInfo) IconsInfo {
+	if n != nil {
+		i.Network = mapNetwork(n)
+	}
+	return i
+}
+
+// WithBluetooth returns a copy of i with the bluetooth icon taken from b.
+func (i IconsInfo) WithBluetooth(b *btinfo.BluetoothInfo) IconsInfo {
+	if b != nil {
+		i.Bluetooth = mapBluetooth(b)
+	}
+	return i
+}
+
 func GetIconsJSON() ([]byte, error) {
 	info := GetIcons()
 	return json.MarshalIndent(info, "", "  ")
@@ -0,0 +1,418 @@
+// Package icontheme finds icon files by name following the freedesktop Icon
+// Theme spec.
+package icontheme
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// extensions are tried in the order the spec gives.
+var extensions = []string{".png", ".svg", ".xpm"}
+
+type dirType int
+
+const (
+	threshold dirType = iota
+	fixed
+	scalable
+)
+
+// subdir is a directory section of index.theme.
+type subdir struct {
+	path                          string
+	typ                           dirType
+	size, scale, minSize, maxSize int
+	threshold                     int
+}
+
+type theme struct {
+	inherits []string
+	subdirs  []subdir
+}
+
+type lookupKey struct {
+	name        string
+	size, scale int
+}
+
+// Resolver looks icons up in a theme, falling back to hicolor and then the
+// pixmaps directories. Themes, directory listings and results are cached.
+type Resolver struct {
+	theme string
+	bases []string
+
+	mu      sync.Mutex
+	themes  map[string]*theme
+	entries map[string]map[string]bool
+	results map[lookupKey]string
+}
+
+// New returns a Resolver for the theme name, hicolor if it is empty.
+func New(name string) *Resolver {
+	if name == "" {
+		name = "hicolor"
+	}
+	return &Resolver{
+		theme:   name,
+		bases:   baseDirs(),
+		themes:  make(map[string]*theme),
+		entries: make(map[string]map[string]bool),
+		results: make(map[lookupKey]string),
+	}
+}
+
+var (
+	defaultResolver *Resolver
+	defaultOnce     sync.Once
+)
+
+// Default returns the Resolver for the icon theme the user picked for GTK.
+func Default() *Resolver {
+	defaultOnce.Do(func() {
+		defaultResolver = New(UserTheme())
+	})
+	return defaultResolver
+}
+
+// Lookup resolves name with the default Resolver.
+func Lookup(name string, size int) string {
+	return Default().Lookup(name, size, 1)
+}
+
+// Theme returns the name of the theme r looks in first.
+func (r *Resolver) Theme() string {
+	return r.theme
+}
+
+// Flush forgets everything read so far, the base directories included, so
+// newly installed icons and themes are found.
+func (r *Resolver) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bases = baseDirs()
+	clear(r.themes)
+	clear(r.entries)
+	clear(r.results)
+}
+
+// Lookup returns the file of the icon name at size and scale, or "" if no
+// theme has it. Absolute paths are returned as they are when they exist.
+func (r *Resolver) Lookup(name string, size, scale int) string {
+	if name == "" {
+		return ""
+	}
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		return ""
+	}
+	size, scale = max(size, 1), max(scale, 1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := lookupKey{name, size, scale}
+	if file, ok := r.results[key]; ok {
+		return file
+	}
+
+	file := r.findHelper(name, size, scale, r.theme, map[string]bool{})
+	if file == "" && r.theme != "hicolor" {
+		file = r.findHelper(name, size, scale, "hicolor", map[string]bool{})
+	}
+	if file == "" {
+		file = r.fallback(name)
+	}
+	r.results[key] = file
+	return file
+}
+
+// findHelper looks in name's theme, then in the themes it inherits from.
+func (r *Resolver) findHelper(name string, size, scale int, themeName string, seen map[string]bool) string {
+	if seen[themeName] {
+		return ""
+	}
+	seen[themeName] = true
+
+	t := r.load(themeName)
+	if t == nil {
+		return ""
+	}
+	if file := r.lookupIcon(name, size, scale, themeName, t); file != "" {
+		return file
+	}
+	for _, parent := range t.inherits {
+		if file := r.findHelper(name, size, scale, parent, seen); file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+// lookupIcon returns an icon of a directory matching the size exactly, else
+// the one from the closest directory.
+func (r *Resolver) lookupIcon(name string, size, scale int, themeName string, t *theme) string {
+	for _, sd := range t.subdirs {
+		if !sd.matches(size, scale) {
+			continue
+		}
+		if file := r.find(themeName, sd.path, name); file != "" {
+			return file
+		}
+	}
+
+	closest, distance := "", int(^uint(0)>>1)
+	for _, sd := range t.subdirs {
+		d := sd.distance(size, scale)
+		if d >= distance {
+			continue
+		}
+		if file := r.find(themeName, sd.path, name); file != "" {
+			closest, distance = file, d
+		}
+	}
+	return closest
+}
+
+// find returns the first file of name in dir of the theme, across the base
+// directories.
+func (r *Resolver) find(themeName, dir, name string) string {
+	for _, base := range r.bases {
+		path := filepath.Join(base, themeName, dir)
+		entries := r.list(path)
+		for _, ext := range extensions {
+			if entries[name+ext] {
+				return filepath.Join(path, name+ext)
+			}
+		}
+	}
+	return ""
+}
+
+// fallback looks for name directly in the base and pixmaps directories.
+func (r *Resolver) fallback(name string) string {
+	for _, dir := range append(r.bases, pixmapDirs()...) {
+		entries := r.list(dir)
+		for _, ext := range extensions {
+			if entries[name+ext] {
+				return filepath.Join(dir, name+ext)
+			}
+		}
+	}
+	return ""
+}
+
+// list returns the file names in dir, reading each directory once.
+func (r *Resolver) list(dir string) map[string]bool {
+	if entries, ok := r.entries[dir]; ok {
+		return entries
+	}
+	var entries map[string]bool
+	if des, err := os.ReadDir(dir); err == nil {
+		entries = make(map[string]bool, len(des))
+		for _, de := range des {
+			entries[de.Name()] = true
+		}
+	}
+	r.entries[dir] = entries
+	return entries
+}
+
+// load parses the index.theme of a theme, from the first base directory
+// that has one.
+func (r *Resolver) load(name string) *theme {
+	if t, ok := r.themes[name]; ok {
+		return t
+	}
+
+	var t *theme
+	for _, base := range r.bases {
+		if f, err := os.Open(filepath.Join(base, name, "index.theme")); err == nil {
+			t = parseIndex(f)
+			f.Close()
+			break
+		}
+	}
+	r.themes[name] = t
+	return t
+}
+
+func parseIndex(f *os.File) *theme {
+	sections := map[string]map[string]string{}
+	var current map[string]string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = map[string]string{}
+			sections[line[1:len(line)-1]] = current
+		case current != nil:
+			if k, v, ok := strings.Cut(line, "="); ok {
+				current[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+
+	head := sections["Icon Theme"]
+	t := &theme{inherits: splitList(head["Inherits"])}
+	dirs := append(splitList(head["Directories"]), splitList(head["ScaledDirectories"])...)
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		keys, ok := sections[dir]
+		if !ok || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		size := atoi(keys["Size"], 0)
+		if size <= 0 {
+			continue
+		}
+		sd := subdir{
+			path:      dir,
+			size:      size,
+			scale:     atoi(keys["Scale"], 1),
+			minSize:   atoi(keys["MinSize"], size),
+			maxSize:   atoi(keys["MaxSize"], size),
+			threshold: atoi(keys["Threshold"], 2),
+		}
+		switch keys["Type"] {
+		case "Fixed":
+			sd.typ = fixed
+		case "Scalable":
+			sd.typ = scalable
+		}
+		t.subdirs = append(t.subdirs, sd)
+	}
+	return t
+}
+
+func (sd subdir) matches(size, scale int) bool {
+	if sd.scale != scale {
+		return false
+	}
+	switch sd.typ {
+	case fixed:
+		return sd.size == size
+	case scalable:
+		return sd.minSize <= size && size <= sd.maxSize
+	default:
+		return sd.size-sd.threshold <= size && size <= sd.size+sd.threshold
+	}
+}
+
+func (sd subdir) distance(size, scale int) int {
+	want := size * scale
+	switch sd.typ {
+	case fixed:
+		return abs(sd.size*sd.scale - want)
+	case scalable:
+		return outside(want, sd.minSize*sd.scale, sd.maxSize*sd.scale)
+	default:
+		return outside(want, (sd.size-sd.threshold)*sd.scale, (sd.size+sd.threshold)*sd.scale)
+	}
+}
+
+// outside is how far v is from the range lo to hi.
+func outside(v, lo, hi int) int {
+	switch {
+	case v < lo:
+		return lo - v
+	case v > hi:
+		return v - hi
+	}
+	return 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func atoi(s string, def int) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return def
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// baseDirs lists where themes are looked for, in the spec's order:
+// $HOME/.icons, then icons under the XDG data directories.
+func baseDirs() []string {
+	home := os.Getenv("HOME")
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local/share")
+	}
+
+	dirs := []string{filepath.Join(home, ".icons"), filepath.Join(dataHome, "icons")}
+	for _, d := range dataDirs() {
+		dirs = append(dirs, filepath.Join(d, "icons"))
+	}
+	return dirs
+}
+
+func pixmapDirs() []string {
+	var dirs []string
+	for _, d := range dataDirs() {
+		dirs = append(dirs, filepath.Join(d, "pixmaps"))
+	}
+	return dirs
+}
+
+func dataDirs() []string {
+	dirs := os.Getenv("XDG_DATA_DIRS")
+	if dirs == "" {
+		dirs = "/usr/local/share:/usr/share"
+	}
+	return filepath.SplitList(dirs)
+}
+
+// UserTheme returns the icon theme set in the GTK settings, or hicolor.
+func UserTheme() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+
+	for _, file := range []string{"gtk-4.0/settings.ini", "gtk-3.0/settings.ini"} {
+		f, err := os.Open(filepath.Join(configHome, file))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			k, v, ok := strings.Cut(scanner.Text(), "=")
+			if ok && strings.TrimSpace(k) == "gtk-icon-theme-name" {
+				f.Close()
+				if v = strings.Trim(strings.TrimSpace(v), `"`); v != "" {
+					return v
+				}
+				return "hicolor"
+			}
+		}
+		f.Close()
+	}
+	return "hicolor"
+}
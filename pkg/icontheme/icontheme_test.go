@@ -0,0 +1,193 @@
+package icontheme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture lays out themes under $HOME/.icons and $XDG_DATA_DIRS/icons and
+// returns the root of the files.
+func fixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(root, "share"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+
+	files := map[string]string{
+		"home/.icons/Child/index.theme": `[Icon Theme]
+Name=Child
+Inherits=Parent
+Directories=16x16/apps,22x22/apps,48x48/apps,scalable/apps
+ScaledDirectories=32x32@2/apps
+
+[16x16/apps]
+Size=16
+Type=Threshold
+
+[22x22/apps]
+Size=22
+Type=Fixed
+
+[48x48/apps]
+Size=48
+Threshold=4
+
+[scalable/apps]
+Size=48
+MinSize=8
+MaxSize=512
+Type=Scalable
+
+[32x32@2/apps]
+Size=32
+Scale=2
+Type=Fixed
+`,
+		"home/.icons/Child/16x16/apps/app.png":       "",
+		"home/.icons/Child/48x48/apps/app.png":       "",
+		"home/.icons/Child/48x48/apps/both.svg":      "",
+		"home/.icons/Child/48x48/apps/both.png":      "",
+		"home/.icons/Child/22x22/apps/fixed.png":     "",
+		"home/.icons/Child/16x16/apps/fixed.png":     "",
+		"home/.icons/Child/scalable/apps/vector.svg": "",
+		"home/.icons/Child/32x32@2/apps/hidpi.png":   "",
+		"home/.icons/Child/48x48/apps/hidpi.png":     "",
+		// The same theme in a later base directory adds icons
+		"share/icons/Child/48x48/apps/app.png":   "",
+		"share/icons/Child/48x48/apps/later.png": "",
+		"share/icons/Parent/index.theme": `[Icon Theme]
+Name=Parent
+Inherits=Child
+Directories=apps
+
+[apps]
+Size=48
+`,
+		"share/icons/Parent/apps/parent-only.png": "",
+		"share/icons/hicolor/index.theme": `[Icon Theme]
+Name=Hicolor
+Directories=48x48/apps
+
+[48x48/apps]
+Size=48
+`,
+		"share/icons/hicolor/48x48/apps/hicolor-only.png": "",
+		"share/icons/loose.png":                           "",
+		"share/pixmaps/pixmap-only.xpm":                   "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLookup(t *testing.T) {
+	root := fixture(t)
+	child := filepath.Join(root, "home/.icons/Child")
+	r := New("Child")
+
+	tests := []struct {
+		name        string
+		size, scale int
+		want        string
+	}{
+		{"app", 48, 1, child + "/48x48/apps/app.png"},
+		{"app", 16, 1, child + "/16x16/apps/app.png"},
+		{"app", 18, 1, child + "/16x16/apps/app.png"},   // within the default threshold of 2
+		{"app", 44, 1, child + "/48x48/apps/app.png"},   // within Threshold=4
+		{"app", 30, 1, child + "/16x16/apps/app.png"},   // closest: 12 away from 18, 14 from 44
+		{"both", 48, 1, child + "/48x48/apps/both.png"}, // png before svg
+		{"fixed", 22, 1, child + "/22x22/apps/fixed.png"},
+		{"fixed", 23, 1, child + "/22x22/apps/fixed.png"}, // Fixed does not match 23, but is closest
+		{"fixed", 17, 1, child + "/16x16/apps/fixed.png"},
+		{"vector", 256, 1, child + "/scalable/apps/vector.svg"},
+		{"hidpi", 32, 2, child + "/32x32@2/apps/hidpi.png"},
+		{"hidpi", 48, 1, child + "/48x48/apps/hidpi.png"},
+		{"later", 48, 1, root + "/share/icons/Child/48x48/apps/later.png"},
+		{"parent-only", 48, 1, root + "/share/icons/Parent/apps/parent-only.png"}, // inherited, despite the cycle
+		{"hicolor-only", 48, 1, root + "/share/icons/hicolor/48x48/apps/hicolor-only.png"},
+		{"loose", 48, 1, root + "/share/icons/loose.png"},
+		{"pixmap-only", 48, 1, root + "/share/pixmaps/pixmap-only.xpm"},
+		{"missing", 48, 1, ""},
+		{"", 48, 1, ""},
+		{root + "/share/icons/loose.png", 48, 1, root + "/share/icons/loose.png"},
+		{root + "/nowhere.png", 48, 1, ""},
+	}
+	for _, tt := range tests {
+		if got := r.Lookup(tt.name, tt.size, tt.scale); got != tt.want {
+			t.Errorf("Lookup(%q, %d, %d) = %q, want %q", tt.name, tt.size, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestLookupWithoutTheme(t *testing.T) {
+	root := fixture(t)
+	r := New("")
+	if r.Theme() != "hicolor" {
+		t.Errorf("theme %q, want hicolor", r.Theme())
+	}
+	if got := r.Lookup("hicolor-only", 48, 1); got != root+"/share/icons/hicolor/48x48/apps/hicolor-only.png" {
+		t.Errorf("hicolor-only = %q", got)
+	}
+	if got := New("Nonexistent").Lookup("app", 48, 1); got != "" {
+		t.Errorf("an unknown theme found %q", got)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	root := fixture(t)
+	r := New("Child")
+	if got := r.Lookup("new", 48, 1); got != "" {
+		t.Fatalf("new = %q before installing it", got)
+	}
+
+	file := filepath.Join(root, "share/icons/hicolor/48x48/apps/new.png")
+	os.WriteFile(file, nil, 0644)
+	if got := r.Lookup("new", 48, 1); got != "" {
+		t.Errorf("new = %q, want the cached miss", got)
+	}
+	r.Flush()
+	if got := r.Lookup("new", 48, 1); got != file {
+		t.Errorf("new = %q after Flush, want %q", got, file)
+	}
+
+	// Flush also picks up changed base directories
+	other := t.TempDir()
+	os.MkdirAll(filepath.Join(other, "icons"), 0755)
+	os.WriteFile(filepath.Join(other, "icons", "moved.png"), nil, 0644)
+	t.Setenv("XDG_DATA_DIRS", other)
+	r.Flush()
+	if got := r.Lookup("moved", 48, 1); got != filepath.Join(other, "icons", "moved.png") {
+		t.Errorf("moved = %q", got)
+	}
+}
+
+func TestUserTheme(t *testing.T) {
+	root := fixture(t)
+	if got := UserTheme(); got != "hicolor" {
+		t.Errorf("without settings: %q", got)
+	}
+
+	write := func(name, content string) {
+		path := filepath.Join(root, "config", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	write("gtk-3.0/settings.ini", "[Settings]\ngtk-icon-theme-name = \"Papirus\"\n")
+	if got := UserTheme(); got != "Papirus" {
+		t.Errorf("gtk-3.0: %q", got)
+	}
+	write("gtk-4.0/settings.ini", "[Settings]\ngtk-icon-theme-name=Adwaita\n")
+	if got := UserTheme(); got != "Adwaita" {
+		t.Errorf("gtk-4.0 first: %q", got)
+	}
+}
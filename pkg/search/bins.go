@@ -3,7 +3,6 @@ package search
 import (
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 )
 
@@ -31,8 +30,8 @@ func searchBins(term string) []Result {
 				continue
 			}
 			name := e.Name()
-			ok, _ := matchScore(toks, name, p)
-			if !ok && term != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(term)) {
+			ok, score := matchScore(toks, name)
+			if !ok {
 				continue
 			}
 			full := filepath.Join(p, name)
@@ -44,16 +43,19 @@ func searchBins(term string) []Result {
 			}
 			seen[full] = true
 			out = append(out, Result{
+				ID:      "bin:" + name,
 				Name:    name,
 				GUI:     false,
 				Comment: "Binary",
 				Type:    "bin",
 				Source:  full,
 				Command: "", // no running the binary
+				score:   score,
 			})
 		}
 	}
 
-	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	sortByName(out)
+	rankResults(out)
 	return out
 }
@@ -54,8 +54,7 @@ func searchClipboardMode(term string) []Result {
 			continue
 		}
 
-		ok, _ := matchScore(toks, preview)
-		if !ok {
+		if !containsAll(toks, preview) {
 			continue
 		}
 
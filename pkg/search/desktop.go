@@ -1,91 +1,149 @@
 package search
 
 import (
-	"bufio"
-	"bytes"
-	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
+
+	"github.com/hoppxi/wigo/pkg/icontheme"
 )
 
+// desktopApp is a parsed .desktop file; root orders entries by the data
+// directory they came from, the first one wins.
+type desktopApp struct {
+	path  string
+	root  int
+	entry *desktopEntry
+}
+
+// Apps searches the desktop apps. The CLI points it at the daemon's index
+// and keeps SearchDesktopApps as the fallback.
+var Apps = SearchDesktopApps
+
+// SearchDesktopApps scans and parses every .desktop file, then searches them.
 func SearchDesktopApps(term string) []Result {
+	dirs := applicationDirs()
+	var apps []desktopApp
+	for _, p := range collectDesktopFiles() {
+		if e := parseDesktopEntry(p); e != nil {
+			e.ID = desktopFileID(p, dirs)
+			apps = append(apps, desktopApp{path: p, root: rootIndex(dirs, p), entry: e})
+		}
+	}
+	slices.SortFunc(apps, compareApps)
+	return searchApps(term, apps)
+}
+
+func compareApps(a, b desktopApp) int {
+	if a.root != b.root {
+		return a.root - b.root
+	}
+	return strings.Compare(a.path, b.path)
+}
+
+// rootIndex returns the position of the directory of dirs holding path,
+// len(dirs) when none does.
+func rootIndex(dirs []string, path string) int {
+	for i, d := range dirs {
+		if d != "" && strings.HasPrefix(path, d+string(filepath.Separator)) {
+			return i
+		}
+	}
+	return len(dirs)
+}
+
+// searchApps matches term against apps, given in lookup order: the first
+// entry of a desktop file ID shadows the others, so a Hidden one in
+// ~/.local/share deletes an app. Each app's actions are results of their own.
+func searchApps(term string, apps []desktopApp) []Result {
 	toks := tokensFrom(term)
-	paths := collectDesktopFiles()
+	desktops := currentDesktops()
 	var out []Result
+	ids := map[string]bool{}
 	seen := map[string]bool{}
-	for _, p := range paths {
-		info := parseDesktopFile(p)
-		if info == nil {
-			continue
-		}
-		if info["Type"] != "Application" {
+	for _, app := range apps {
+		e := app.entry
+		if ids[e.ID] {
 			continue
 		}
-		if strings.EqualFold(info["NoDisplay"], "true") {
+		ids[e.ID] = true
+		if !e.visible(desktops) {
 			continue
 		}
-		name := info["Name"]
+
+		name := e.Name
 		if name == "" {
-			name = filepath.Base(p)
+			name = e.ID
+		}
+		execCmd := sanitizeExecField(e.Exec)
+		keywords := strings.Join(e.Keywords, " ")
+		ok, score := matchFields(toks, []string{name, e.GenericName, keywords}, []string{e.Comment, execCmd})
+		if ok {
+			launch := execCmd
+			if launch == "" {
+				launch = name
+			}
+			key := strings.ToLower(launch + "|" + name)
+			if !seen[key] {
+				seen[key] = true
+				out = append(out, Result{
+					ID:      "app:" + e.ID,
+					Name:    name,
+					GUI:     true,
+					Type:    "app",
+					Comment: e.Comment,
+					Source:  app.path,
+					Command: launch,
+					Icon:    resolveIcon(e.Icon),
+					score:   score,
+				})
+			}
 		}
-		comment := info["Comment"]
 
-		execCmd := sanitizeExecField(info["Exec"])
-		ok, score := matchScore(toks, name, execCmd, comment, p)
-		if !ok {
-			continue
-		}
-		launch := execCmd
-		if launch == "" {
-			launch = name
-		}
-		key := strings.ToLower(launch + "|" + name)
-		if seen[key] {
-			continue
+		for _, a := range e.Actions {
+			full := name + " — " + a.Name
+			ok, score := matchScore(toks, full, e.GenericName, keywords)
+			if !ok {
+				continue
+			}
+			icon := a.Icon
+			if icon == "" {
+				icon = e.Icon
+			}
+			out = append(out, Result{
+				ID:      "app:" + e.ID + ":" + a.ID,
+				Name:    full,
+				GUI:     true,
+				Type:    "app",
+				Comment: e.Comment,
+				Source:  app.path,
+				Command: sanitizeExecField(a.Exec),
+				Icon:    resolveIcon(icon),
+				score:   score,
+			})
 		}
-		seen[key] = true
-
-		iconPath := info["Icon"]
-		out = append(out, Result{
-			Name:    name,
-			GUI:     true,
-			Type:    "app",
-			Comment: comment,
-			Source:  fmt.Sprintf("%s|score:%d", p, score),
-			Command: launch,
-			Icon:    iconPath,
-		})
 	}
-	// sort by score desc then name
-	sort.Slice(out, func(i, j int) bool {
-		si := extractScore(out[i].Source)
-		sj := extractScore(out[j].Source)
-		if si != sj {
-			return si > sj
-		}
-		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
-	})
+	sortByName(out)
+	rankResults(out)
 	return out
 }
 
-func extractScore(src string) int {
-	parts := strings.SplitSeq(src, "|")
-	for p := range parts {
-		if after, ok := strings.CutPrefix(p, "score:"); ok {
-			n, _ := strconv.Atoi(after)
-			return n
-		}
-	}
-	return 0
+// iconSize is the size icons are looked up at, the launcher shows them
+// smaller.
+const iconSize = 48
+
+// resolveIcon turns an Icon value into a file of the icon theme, "" when
+// there is none.
+func resolveIcon(icon string) string {
+	return icontheme.Lookup(icon, iconSize)
 }
 
-func collectDesktopFiles() []string {
-	var dirs []string
+// applicationDirs lists the applications directories of the XDG data
+// directories and the Nix profiles, most important first.
+func applicationDirs() []string {
 	xdg := os.Getenv("XDG_DATA_DIRS")
 	if xdg == "" {
 		xdg = "/usr/local/share:/usr/share"
@@ -97,6 +155,8 @@ func collectDesktopFiles() []string {
 	if exists("/run/current-system/sw/share") {
 		parts = append(parts, "/run/current-system/sw/share")
 	}
+
+	var dirs []string
 	seen := map[string]bool{}
 	for _, p := range parts {
 		if p == "" {
@@ -104,14 +164,36 @@ func collectDesktopFiles() []string {
 		}
 		if !seen[p] {
 			seen[p] = true
-			dirs = append(dirs, p)
+			dirs = append(dirs, filepath.Join(p, "applications"))
 		}
 	}
+	return dirs
+}
 
+func collectDesktopFiles() []string {
+	return walkDesktopFiles(append(applicationDirs(), storeApplicationDirs()...))
+}
+
+// storeApplicationDirs lists the applications directory of every Nix store
+// path, including packages no profile links to.
+func storeApplicationDirs() []string {
+	if !exists("/nix/store") {
+		return nil
+	}
+	var dirs []string
+	entries, _ := os.ReadDir("/nix/store")
+	for _, e := range entries {
+		dirs = append(dirs, filepath.Join("/nix/store", e.Name(), "share", "applications"))
+	}
+	return dirs
+}
+
+// walkDesktopFiles returns the .desktop files under dirs, walking them
+// concurrently.
+func walkDesktopFiles(dirs []string) []string {
 	outCh := make(chan string, 512)
 	var wg sync.WaitGroup
-	for _, d := range dirs {
-		appdir := filepath.Join(d, "applications")
+	for _, appdir := range dirs {
 		wg.Add(1)
 		go func(ad string) {
 			defer wg.Done()
@@ -130,30 +212,6 @@ func collectDesktopFiles() []string {
 		}(appdir)
 	}
 
-	// include nix store scan
-	if exists("/nix/store") {
-		entries, _ := os.ReadDir("/nix/store")
-		for _, e := range entries {
-			appdir := filepath.Join("/nix/store", e.Name(), "share", "applications")
-			wg.Add(1)
-			go func(ad string) {
-				defer wg.Done()
-				filepath.WalkDir(ad, func(path string, de fs.DirEntry, err error) error {
-					if err != nil {
-						return nil
-					}
-					if de.IsDir() {
-						return nil
-					}
-					if strings.HasSuffix(path, ".desktop") {
-						outCh <- path
-					}
-					return nil
-				})
-			}(appdir)
-		}
-	}
-
 	go func() {
 		wg.Wait()
 		close(outCh)
@@ -169,39 +227,3 @@ func collectDesktopFiles() []string {
 	}
 	return out
 }
-
-func parseDesktopFile(path string) map[string]string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil
-	}
-	out := map[string]string{}
-	in := false
-	sc := bufio.NewScanner(bytes.NewReader(data))
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" {
-			continue
-		}
-		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
-			continue
-		}
-		if line == "[Desktop Entry]" {
-			in = true
-			continue
-		}
-		if strings.HasPrefix(line, "[") {
-			in = false
-			continue
-		}
-		if !in {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		out[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-	}
-	return out
-}
@@ -86,19 +86,10 @@ func searchEmojiMode(rawTerm string) []Result {
 			continue
 		}
 
+		score := 0
 		if term != "" {
-			termMatch := false
-			if strings.Contains(strings.ToLower(item.Text), term) {
-				termMatch = true
-			} else {
-				for _, k := range item.Keywords {
-					if strings.Contains(strings.ToLower(k), term) {
-						termMatch = true
-						break
-					}
-				}
-			}
-			match = termMatch // The final match depends on passing the text search
+			// The final match depends on passing the text search
+			match, score = matchScore(tokensFrom(term), append([]string{item.Text}, item.Keywords...)...)
 		}
 
 		if match {
@@ -114,6 +105,7 @@ func searchEmojiMode(rawTerm string) []Result {
 			commandToCopy := fmt.Sprintf("echo -n \"%s\" | wl-copy", contentToCopy)
 
 			results = append(results, Result{
+				ID:      "emoji:" + item.Emoji,
 				Name:    outputName,
 				GUI:     false,
 				Type:    "emoji",
@@ -121,14 +113,14 @@ func searchEmojiMode(rawTerm string) []Result {
 				Command: commandToCopy, // Command to execute upon selection
 				Icon:    item.Emoji,    // Icon is always the emoji character for visualization
 				Comment: item.Unicode,
+				score:   score,
 			})
-
-			// STOP if we reached the limit
-			if len(results) >= limit {
-				break
-			}
 		}
 	}
 
+	rankResults(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
 	return results
 }
@@ -0,0 +1,277 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// desktopEntry is a .desktop file parsed by the Desktop Entry spec, with
+// its localized keys resolved for the current locale.
+type desktopEntry struct {
+	File string // the .desktop file
+	ID   string // desktop file ID, see desktopFileID
+
+	Type        string
+	Name        string
+	GenericName string
+	Comment     string
+	Icon        string
+	// Exec keeps the string escapes, parseExec undoes them with the quoting
+	Exec           string
+	TryExec        string
+	Path           string // working directory
+	StartupWMClass string
+
+	Keywords   []string
+	Categories []string
+	OnlyShowIn []string
+	NotShowIn  []string
+
+	Terminal        bool
+	NoDisplay       bool
+	Hidden          bool
+	DBusActivatable bool
+
+	Actions []desktopAction
+}
+
+// desktopAction is a [Desktop Action id] group listed in Actions.
+type desktopAction struct {
+	ID   string
+	Name string
+	Icon string
+	Exec string
+}
+
+// desktopGroup maps each key to its values by locale, "" being the
+// unlocalized one.
+type desktopGroup map[string]map[string]string
+
+// parseDesktopEntry reads a .desktop file; nil when it cannot be read or
+// has no [Desktop Entry] group.
+func parseDesktopEntry(path string) *desktopEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	groups := map[string]desktopGroup{}
+	var cur desktopGroup
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+			if groups[name] == nil {
+				groups[name] = desktopGroup{}
+			}
+			cur = groups[name]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		locale := ""
+		if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+			key, locale = key[:i], key[i+1:len(key)-1]
+		}
+		if cur[key] == nil {
+			cur[key] = map[string]string{}
+		}
+		// The first of duplicate keys wins
+		if _, dup := cur[key][locale]; !dup {
+			cur[key][locale] = value
+		}
+	}
+
+	g, ok := groups["Desktop Entry"]
+	if !ok {
+		return nil
+	}
+	locales := localeVariants()
+	e := &desktopEntry{
+		File:            path,
+		ID:              desktopFileID(path, nil),
+		Type:            g.str("Type"),
+		Name:            g.localized("Name", locales),
+		GenericName:     g.localized("GenericName", locales),
+		Comment:         g.localized("Comment", locales),
+		Icon:            g.localized("Icon", locales),
+		Exec:            g.raw("Exec"),
+		TryExec:         g.str("TryExec"),
+		Path:            g.str("Path"),
+		StartupWMClass:  g.str("StartupWMClass"),
+		Keywords:        splitList(g.localizedRaw("Keywords", locales)),
+		Categories:      splitList(g.raw("Categories")),
+		OnlyShowIn:      splitList(g.raw("OnlyShowIn")),
+		NotShowIn:       splitList(g.raw("NotShowIn")),
+		Terminal:        g.raw("Terminal") == "true",
+		NoDisplay:       g.raw("NoDisplay") == "true",
+		Hidden:          g.raw("Hidden") == "true",
+		DBusActivatable: g.raw("DBusActivatable") == "true",
+	}
+
+	for _, id := range splitList(g.raw("Actions")) {
+		ag, ok := groups["Desktop Action "+id]
+		if !ok {
+			continue
+		}
+		a := desktopAction{
+			ID:   id,
+			Name: ag.localized("Name", locales),
+			Icon: ag.localized("Icon", locales),
+			Exec: ag.raw("Exec"),
+		}
+		if a.Name != "" {
+			e.Actions = append(e.Actions, a)
+		}
+	}
+	return e
+}
+
+// desktopFileID is the ID the Desktop Entry spec gives path: its path below
+// the applications directory among dirs, with / turned into -, so that
+// applications/kde4/foo.desktop is kde4-foo.desktop. Without a matching dir
+// it is taken below the last applications directory of path.
+func desktopFileID(path string, dirs []string) string {
+	for _, d := range dirs {
+		if rel, ok := strings.CutPrefix(path, d+string(filepath.Separator)); ok && d != "" {
+			return strings.ReplaceAll(rel, "/", "-")
+		}
+	}
+	if i := strings.LastIndex(path, "/applications/"); i >= 0 {
+		return strings.ReplaceAll(path[i+len("/applications/"):], "/", "-")
+	}
+	return filepath.Base(path)
+}
+
+func (g desktopGroup) raw(key string) string {
+	return g[key][""]
+}
+
+func (g desktopGroup) str(key string) string {
+	return unescapeString(g.raw(key))
+}
+
+func (g desktopGroup) localizedRaw(key string, locales []string) string {
+	for _, l := range locales {
+		if v, ok := g[key][l]; ok {
+			return v
+		}
+	}
+	return g.raw(key)
+}
+
+func (g desktopGroup) localized(key string, locales []string) string {
+	return unescapeString(g.localizedRaw(key, locales))
+}
+
+// splitList splits a ;-separated value, honouring \; and the string escapes.
+func splitList(value string) []string {
+	var out []string
+	var cur strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ';':
+			cur.WriteByte(';')
+			i++
+		case value[i] == '\\' && i+1 < len(value):
+			cur.WriteString(value[i : i+2])
+			i++
+		case value[i] == ';':
+			if s := unescapeString(cur.String()); s != "" {
+				out = append(out, s)
+			}
+			cur.Reset()
+		default:
+			cur.WriteByte(value[i])
+		}
+	}
+	if s := unescapeString(cur.String()); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// localeVariants returns the keys to try for LC_MESSAGES, best first: for
+// sr_YU@Latn that is sr_YU@Latn, sr_YU, sr@Latn and sr.
+func localeVariants() []string {
+	locale := ""
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(env); locale != "" {
+			break
+		}
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+
+	locale, modifier, _ := strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	lang, country, _ := strings.Cut(locale, "_")
+
+	var out []string
+	if country != "" && modifier != "" {
+		out = append(out, lang+"_"+country+"@"+modifier)
+	}
+	if country != "" {
+		out = append(out, lang+"_"+country)
+	}
+	if modifier != "" {
+		out = append(out, lang+"@"+modifier)
+	}
+	return append(out, lang)
+}
+
+// currentDesktops returns the desktops of XDG_CURRENT_DESKTOP.
+func currentDesktops() []string {
+	return strings.FieldsFunc(os.Getenv("XDG_CURRENT_DESKTOP"), func(r rune) bool { return r == ':' })
+}
+
+// visible tells whether the entry is an application to show on desktops:
+// not hidden, meant for one of them and with its TryExec installed.
+func (e *desktopEntry) visible(desktops []string) bool {
+	if e.Type != "Application" || e.Hidden || e.NoDisplay {
+		return false
+	}
+	shownIn := func(list []string) bool {
+		return slices.ContainsFunc(desktops, func(d string) bool {
+			return slices.ContainsFunc(list, func(s string) bool { return strings.EqualFold(s, d) })
+		})
+	}
+	if len(e.OnlyShowIn) > 0 && !shownIn(e.OnlyShowIn) {
+		return false
+	}
+	if shownIn(e.NotShowIn) {
+		return false
+	}
+	if e.TryExec != "" {
+		if _, err := exec.LookPath(expandHome(e.TryExec)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// action returns the action with the given ID.
+func (e *desktopEntry) action(id string) (desktopAction, bool) {
+	for _, a := range e.Actions {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return desktopAction{}, false
+}
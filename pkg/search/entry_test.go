@@ -0,0 +1,234 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDesktopFileID(t *testing.T) {
+	dirs := []string{"/home/me/.local/share/applications", "/usr/share/applications"}
+	tests := []struct {
+		path string
+		dirs []string
+		want string
+	}{
+		{"/usr/share/applications/firefox.desktop", dirs, "firefox.desktop"},
+		{"/usr/share/applications/kde4/foo.desktop", dirs, "kde4-foo.desktop"},
+		{"/usr/share/applications/a/b/c.desktop", dirs, "a-b-c.desktop"},
+		{"/nix/store/abc-foo/share/applications/sub/foo.desktop", nil, "sub-foo.desktop"},
+		{"/somewhere/else/foo.desktop", nil, "foo.desktop"},
+	}
+	for _, tt := range tests {
+		if got := desktopFileID(tt.path, tt.dirs); got != tt.want {
+			t.Errorf("desktopFileID(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFindIn(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"firefox.desktop", "kde4/foo.desktop", "my-app/bar-baz.desktop"} {
+		path := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("[Desktop Entry]\n"), 0644)
+	}
+	tests := map[string]string{
+		"firefox.desktop":        "firefox.desktop",
+		"kde4-foo.desktop":       "kde4/foo.desktop",
+		"my-app-bar-baz.desktop": "my-app/bar-baz.desktop",
+		"missing.desktop":        "",
+		"kde4-missing.desktop":   "",
+	}
+	for id, want := range tests {
+		got := findIn(dir, id)
+		if want != "" {
+			want = filepath.Join(dir, want)
+		}
+		if got != want {
+			t.Errorf("findIn(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"GNOME;KDE;", []string{"GNOME", "KDE"}},
+		{"GNOME;KDE", []string{"GNOME", "KDE"}},
+		{`a\;b;c`, []string{"a;b", "c"}},
+		{`one\stwo;x\;`, []string{"one two", "x;"}},
+		{`a\\;b`, []string{`a\`, "b"}},
+		{";;a;;", []string{"a"}},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleVariants(t *testing.T) {
+	tests := []struct {
+		lcAll, lcMessages, lang string
+		want                    []string
+	}{
+		{"", "", "sr_YU.UTF-8@Latn", []string{"sr_YU@Latn", "sr_YU", "sr@Latn", "sr"}},
+		{"", "", "de_DE.UTF-8", []string{"de_DE", "de"}},
+		{"", "", "de@euro", []string{"de@euro", "de"}},
+		{"", "", "fr", []string{"fr"}},
+		{"", "", "C", nil},
+		{"", "", "", nil},
+		{"", "pt_BR", "de_DE", []string{"pt_BR", "pt"}},
+		{"es_ES", "pt_BR", "de_DE", []string{"es_ES", "es"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", tt.lcMessages)
+		t.Setenv("LANG", tt.lang)
+		if got := localeVariants(); !slices.Equal(got, tt.want) {
+			t.Errorf("localeVariants(%q, %q, %q) = %q, want %q", tt.lcAll, tt.lcMessages, tt.lang, got, tt.want)
+		}
+	}
+}
+
+const firefoxEntry = `# comment
+[Desktop Entry]
+Type=Application
+Name=Firefox
+Name[de]=Feuerfuchs
+Name[sr_YU@Latn]=Vatrena lisica
+Name[sr]=Лисица
+GenericName = Web Browser
+Comment=Browse\sthe web
+Keywords=Internet;WWW;a\;b;
+Keywords[de]=Netz;
+Exec=firefox %u
+Icon=firefox
+Terminal=false
+StartupWMClass=firefox
+OnlyShowIn=Hyprland;GNOME;
+Actions=new-window;missing;nameless;
+Name=Duplicate
+
+[Desktop Action new-window]
+Name=New Window
+Name[de]=Neues Fenster
+Exec=firefox --new-window
+
+[Desktop Action nameless]
+Exec=true
+
+[Other Group]
+Name=Not this
+`
+
+func writeEntry(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "applications", "firefox.desktop")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseDesktopEntry(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "C")
+	path := writeEntry(t, firefoxEntry)
+
+	e := parseDesktopEntry(path)
+	if e == nil {
+		t.Fatal("no entry")
+	}
+	if e.ID != "firefox.desktop" || e.Name != "Firefox" || e.GenericName != "Web Browser" ||
+		e.Comment != "Browse the web" || e.Exec != "firefox %u" || e.StartupWMClass != "firefox" {
+		t.Errorf("entry = %+v", e)
+	}
+	if !slices.Equal(e.Keywords, []string{"Internet", "WWW", "a;b"}) {
+		t.Errorf("keywords = %q", e.Keywords)
+	}
+	if !slices.Equal(e.OnlyShowIn, []string{"Hyprland", "GNOME"}) {
+		t.Errorf("OnlyShowIn = %q", e.OnlyShowIn)
+	}
+	if len(e.Actions) != 1 || e.Actions[0].ID != "new-window" || e.Actions[0].Name != "New Window" {
+		t.Errorf("actions = %+v", e.Actions)
+	}
+
+	t.Setenv("LANG", "de_DE.UTF-8")
+	e = parseDesktopEntry(path)
+	if e.Name != "Feuerfuchs" || e.Actions[0].Name != "Neues Fenster" || !slices.Equal(e.Keywords, []string{"Netz"}) {
+		t.Errorf("de: name %q, action %q, keywords %q", e.Name, e.Actions[0].Name, e.Keywords)
+	}
+	t.Setenv("LANG", "sr_YU.UTF-8@Latn")
+	if e = parseDesktopEntry(path); e.Name != "Vatrena lisica" {
+		t.Errorf("sr_YU@Latn: name %q", e.Name)
+	}
+	t.Setenv("LANG", "sr_RS")
+	if e = parseDesktopEntry(path); e.Name != "Лисица" {
+		t.Errorf("sr_RS: name %q", e.Name)
+	}
+
+	if parseDesktopEntry(writeEntry(t, "[Other]\nName=x\n")) != nil {
+		t.Error("a file without [Desktop Entry] parsed")
+	}
+	if parseDesktopEntry(filepath.Join(t.TempDir(), "missing.desktop")) != nil {
+		t.Error("a missing file parsed")
+	}
+}
+
+func TestVisible(t *testing.T) {
+	app := func(mod func(e *desktopEntry)) *desktopEntry {
+		e := &desktopEntry{Type: "Application", Name: "App"}
+		mod(e)
+		return e
+	}
+	tests := []struct {
+		name     string
+		e        *desktopEntry
+		desktops []string
+		want     bool
+	}{
+		{"plain", app(func(e *desktopEntry) {}), nil, true},
+		{"link", app(func(e *desktopEntry) { e.Type = "Link" }), nil, false},
+		{"hidden", app(func(e *desktopEntry) { e.Hidden = true }), nil, false},
+		{"no display", app(func(e *desktopEntry) { e.NoDisplay = true }), nil, false},
+		{"only here", app(func(e *desktopEntry) { e.OnlyShowIn = []string{"KDE", "Hyprland"} }), []string{"Hyprland"}, true},
+		{"only elsewhere", app(func(e *desktopEntry) { e.OnlyShowIn = []string{"KDE"} }), []string{"Hyprland"}, false},
+		{"only, no desktop", app(func(e *desktopEntry) { e.OnlyShowIn = []string{"KDE"} }), nil, false},
+		{"not here", app(func(e *desktopEntry) { e.NotShowIn = []string{"GNOME"} }), []string{"ubuntu", "GNOME"}, false},
+		{"not elsewhere", app(func(e *desktopEntry) { e.NotShowIn = []string{"GNOME"} }), []string{"Hyprland"}, true},
+		{"try exec found", app(func(e *desktopEntry) { e.TryExec = "sh" }), nil, true},
+		{"try exec missing", app(func(e *desktopEntry) { e.TryExec = "/no/such/binary" }), nil, false},
+	}
+	for _, tt := range tests {
+		if got := tt.e.visible(tt.desktops); got != tt.want {
+			t.Errorf("%s: visible = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchAppsShadowsByID(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	user := &desktopEntry{ID: "kde4-foo.desktop", Type: "Application", Name: "Foo", Hidden: true}
+	system := &desktopEntry{ID: "kde4-foo.desktop", Type: "Application", Name: "Foo", Exec: "foo"}
+	other := &desktopEntry{ID: "foo.desktop", Type: "Application", Name: "Foo Too", Exec: "foo-too"}
+	apps := []desktopApp{
+		{path: "/home/me/.local/share/applications/kde4-foo.desktop", root: 0, entry: user},
+		{path: "/usr/share/applications/kde4/foo.desktop", root: 1, entry: system},
+		{path: "/usr/share/applications/foo.desktop", root: 1, entry: other},
+	}
+	var got []string
+	for _, r := range searchApps("foo", apps) {
+		got = append(got, r.ID)
+	}
+	if !slices.Equal(got, []string{"app:foo.desktop"}) {
+		t.Errorf("got %q, want only app:foo.desktop", got)
+	}
+}
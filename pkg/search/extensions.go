@@ -27,6 +27,19 @@ func handleScriptExtension(ext ExtConfig, query string) []Result {
 	if err := json.Unmarshal(out, &results); err != nil {
 		return []Result{{Name: "Invalid JSON from Extension", Source: ext.Name}}
 	}
+
+	// The script filtered already; matching names and frequent picks
+	// only move up
+	toks := tokensFrom(query)
+	for i := range results {
+		if results[i].ID == "" {
+			results[i].ID = "ext:" + ext.Name + ":" + results[i].Name
+		}
+		if ok, score := matchScore(toks, results[i].Name); ok {
+			results[i].score = score
+		}
+	}
+	rankResults(results)
 	return results
 }
 
@@ -37,6 +50,7 @@ func handleFolderExtension(ext ExtConfig, query string) []Result {
 		return []Result{{Name: "Folder Error", Source: ext.Name, Command: "echo folder not found"}}
 	}
 
+	toks := tokensFrom(query)
 	var results []Result
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -49,13 +63,14 @@ func handleFolderExtension(ext ExtConfig, query string) []Result {
 			continue
 		}
 
-		// Basic filter for the file list
-		if query != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+		ok, score := matchScore(toks, name)
+		if !ok {
 			continue
 		}
 
 		fullPath := filepath.Join(dirPath, name)
 		results = append(results, Result{
+			ID:      "ext:" + ext.Name + ":" + name,
 			Name:    name,
 			GUI:     true,
 			Type:    "file",
@@ -63,7 +78,9 @@ func handleFolderExtension(ext ExtConfig, query string) []Result {
 			Command: strings.ReplaceAll(ext.OnSelect, "{}", fullPath),
 			Comment: "extension: " + ext.Name,
 			Icon:    fullPath,
+			score:   score,
 		})
 	}
+	rankResults(results)
 	return results
 }
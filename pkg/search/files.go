@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io/fs"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 )
@@ -26,9 +25,11 @@ func searchFilesMode(arg string) []Result {
 	if max <= 0 {
 		max = 20
 	}
-	term := strings.TrimSpace(rem)
+	toks := tokensFrom(rem)
+	// more matches than shown are collected, so the best ones can be picked
+	candidates := max * 20
 
-	// walk concurrently but stop when max reached
+	// walk concurrently but stop when enough candidates were found
 	found := make(chan Result, max)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -71,9 +72,15 @@ func searchFilesMode(arg string) []Result {
 				return nil
 			}
 			name := filepath.Base(path)
-			if term == "" || strings.Contains(strings.ToLower(name), strings.ToLower(term)) || strings.Contains(strings.ToLower(path), strings.ToLower(term)) {
+			// Names match fuzzily, whole paths only literally, or nearly
+			// every file would match
+			ok, score := matchScore(toks, name)
+			if !ok {
+				ok = containsAll(toks, path)
+			}
+			if ok {
 				mu.Lock()
-				if count >= max {
+				if count >= candidates {
 					mu.Unlock()
 					close(done)
 					return errors.New("max reached")
@@ -81,12 +88,14 @@ func searchFilesMode(arg string) []Result {
 				count++
 				mu.Unlock()
 				found <- Result{
+					ID:      "file:" + path,
 					Name:    name,
 					GUI:     false,
 					Type:    "file",
 					Source:  path,
 					Command: fileOpenCommand(path),
 					Comment: "opening" + path,
+					score:   score,
 				}
 			}
 			return nil
@@ -101,9 +110,6 @@ func searchFilesMode(arg string) []Result {
 	out := []Result{}
 	for r := range found {
 		out = append(out, r)
-		if len(out) >= max {
-			break
-		}
 	}
 	// ensure unique by source
 	uniq := map[string]Result{}
@@ -114,7 +120,10 @@ func searchFilesMode(arg string) []Result {
 	for _, v := range uniq {
 		final = append(final, v)
 	}
-	// sort by name
-	sort.Slice(final, func(i, j int) bool { return strings.ToLower(final[i].Name) < strings.ToLower(final[j].Name) })
+	sortByName(final)
+	rankResults(final)
+	if len(final) > max {
+		final = final[:max]
+	}
 	return final
 }
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/utils"
+)
+
+// frecencyEntry is how often and when a result was last launched.
+type frecencyEntry struct {
+	Count int   `json:"count"`
+	Last  int64 `json:"last"`
+}
+
+// maxFrecencyEntries bounds the store; the least valuable entries go first.
+const maxFrecencyEntries = 2000
+
+func frecencyPath() string {
+	return filepath.Join(homeDir(), ".local", "share", "wigo", "frecency.json")
+}
+
+func loadFrecency() map[string]frecencyEntry {
+	store := map[string]frecencyEntry{}
+	data, err := os.ReadFile(frecencyPath())
+	if err == nil {
+		json.Unmarshal(data, &store)
+	}
+	return store
+}
+
+// RecordLaunch counts a launch of the result with the given ID, so it ranks
+// higher next time. Concurrent launches are serialized with a lock file.
+func RecordLaunch(id string) error {
+	if id == "" {
+		return nil
+	}
+	path := frecencyPath()
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	store := loadFrecency()
+	e := store[id]
+	e.Count++
+	e.Last = time.Now().Unix()
+	store[id] = e
+
+	if len(store) > maxFrecencyEntries {
+		now := time.Now()
+		ids := make([]string, 0, len(store))
+		for k := range store {
+			ids = append(ids, k)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return store[ids[i]].value(now) < store[ids[j]].value(now)
+		})
+		for _, k := range ids[:len(store)-maxFrecencyEntries] {
+			delete(store, k)
+		}
+	}
+
+	data, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+// value weighs the launch count by how recent the last launch was, with
+// the age buckets of Firefox's frecency.
+func (e frecencyEntry) value(now time.Time) float64 {
+	age := now.Sub(time.Unix(e.Last, 0))
+	weight := 10.0
+	switch {
+	case age < 4*time.Hour:
+		weight = 100
+	case age < 24*time.Hour:
+		weight = 70
+	case age < 7*24*time.Hour:
+		weight = 50
+	case age < 30*24*time.Hour:
+		weight = 30
+	}
+	return float64(e.Count) * weight
+}
+
+// frecencyBonus turns a frecency value into points comparable to match
+// scores: one launch today is worth about one well placed character, a
+// favourite app several.
+func frecencyBonus(e frecencyEntry, now time.Time) int {
+	if e.Count == 0 {
+		return 0
+	}
+	return min(80, int(16*math.Log2(1+e.value(now)/100)))
+}
+
+// rankResults adds the frecency bonus to the match score of every result
+// and sorts them best first, keeping the order of equal ones.
+func rankResults(results []Result) {
+	store := loadFrecency()
+	now := time.Now()
+	for i := range results {
+		results[i].score += frecencyBonus(store[results[i].ID], now)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+}
+
+func sortByName(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+}
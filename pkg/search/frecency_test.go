@@ -0,0 +1,45 @@
+package search
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordLaunchConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	const launches = 20
+	var wg sync.WaitGroup
+	for range launches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := RecordLaunch("app:firefox.desktop"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := loadFrecency()["app:firefox.desktop"].Count; got != launches {
+		t.Errorf("count = %d, want %d", got, launches)
+	}
+}
+
+func TestFrecencyBonus(t *testing.T) {
+	now := time.Now()
+	recent := frecencyEntry{Count: 1, Last: now.Unix()}
+	old := frecencyEntry{Count: 1, Last: now.AddDate(0, -2, 0).Unix()}
+	favourite := frecencyEntry{Count: 500, Last: now.Unix()}
+
+	if b := frecencyBonus(frecencyEntry{}, now); b != 0 {
+		t.Errorf("bonus of a never launched result = %d", b)
+	}
+	if frecencyBonus(recent, now) <= frecencyBonus(old, now) {
+		t.Error("a launch today should weigh more than one two months ago")
+	}
+	if b := frecencyBonus(favourite, now); b != 80 {
+		t.Errorf("bonus of a favourite = %d, want the cap of 80", b)
+	}
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores of fuzzyScore, in the spirit of fzf: every matched character earns
+// scoreMatch plus the bonus of its position, gaps cost.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary    = 8  // after a space, -, _, ., / or at the start
+	bonusCamel       = 7  // an upper case letter after a lower case one, a digit after a letter
+	bonusConsecutive = 4  // right after the previous match
+	bonusFirstChar   = 10 // extra for matching the very first character
+)
+
+// fuzzyScore matches pattern against text as a case-insensitive subsequence
+// and returns the score of the best alignment. Matches at word boundaries,
+// camel case humps and runs of consecutive characters score higher, gaps
+// lower. The first character has to match at a boundary or hump, so "ffx"
+// finds Firefox but "ls" does not find "Visual Studio", unless pattern is a
+// plain substring of text. ok is false when nothing matches.
+func fuzzyScore(pattern, text string) (score int, ok bool) {
+	if score, ok := alignScore(pattern, text, true); ok {
+		return score, true
+	}
+	if strings.Contains(strings.ToLower(text), strings.ToLower(pattern)) {
+		return alignScore(pattern, text, false)
+	}
+	return 0, false
+}
+
+// alignScore scores the best alignment of pattern in text; anchored only
+// lets the first character match at a boundary or hump.
+func alignScore(pattern, text string, anchored bool) (score int, ok bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, true
+	}
+	t := []rune(text)
+	n, m := len(p), len(t)
+	if n > m {
+		return 0, false
+	}
+
+	for i := range p {
+		p[i] = unicode.ToLower(p[i])
+	}
+	lower := make([]rune, m)
+	bonus := make([]int, m)
+	for j, r := range t {
+		lower[j] = unicode.ToLower(r)
+		bonus[j] = positionBonus(t, j)
+	}
+
+	// Quick rejection before the quadratic part
+	k := 0
+	for j := 0; j < m && k < n; j++ {
+		if lower[j] == p[k] {
+			k++
+		}
+	}
+	if k < n {
+		return 0, false
+	}
+
+	// prev[j] is the best score of p[:i] ending with a match at j,
+	// or negInf when there is none
+	const negInf = -1 << 30
+	prev := make([]int, m)
+	cur := make([]int, m)
+	for j := range m {
+		prev[j] = negInf
+		if lower[j] == p[0] && (!anchored || bonus[j] >= bonusCamel) {
+			prev[j] = scoreMatch + bonus[j]
+		}
+	}
+
+	for i := 1; i < n; i++ {
+		gap := negInf // best prev[k] for k < j-1, minus the gap to j
+		for j := range m {
+			cur[j] = negInf
+			if j >= 2 && prev[j-2] > negInf {
+				gap = max(gap+scoreGapExtension, prev[j-2]+scoreGapStart)
+			} else if gap > negInf {
+				gap += scoreGapExtension
+			}
+			if lower[j] != p[i] || j == 0 {
+				continue
+			}
+			best := gap
+			if prev[j-1] > negInf {
+				best = max(best, prev[j-1]+bonusConsecutive)
+			}
+			if best > negInf {
+				cur[j] = best + scoreMatch + bonus[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	score = negInf
+	for _, s := range prev {
+		score = max(score, s)
+	}
+	return score, score > negInf
+}
+
+func positionBonus(t []rune, j int) int {
+	if j == 0 {
+		return bonusBoundary + bonusFirstChar
+	}
+	before, r := t[j-1], t[j]
+	switch {
+	case before == ' ' || before == '-' || before == '_' || before == '.' || before == '/':
+		return bonusBoundary
+	case unicode.IsLower(before) && unicode.IsUpper(r):
+		return bonusCamel
+	case unicode.IsLetter(before) && unicode.IsDigit(r):
+		return bonusCamel
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+	}{
+		{"ffx", "Firefox", true},
+		{"vsc", "Visual Studio Code", true},
+		{"FIRE", "firefox", true},
+		{"fox", "Firefox", true},       // a substring inside a word
+		{"efo", "Firefox", true},       // likewise
+		{"ls", "Visual Studio", false}, // l is inside a word
+		{"top", "Calculator", false},
+		{"ce", "Calculator", false},
+		{"ce", "Visual Studio Code", true},
+		{"gs", "GoogleSheets", true}, // camel case hump
+		{"v2", "mpv2", true},         // digit after a letter
+		{"", "anything", true},
+		{"long pattern", "short", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.pattern, tt.text); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyScoreOrder(t *testing.T) {
+	// better first
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"code", "Code", "Visual Studio Code"},
+		{"fi", "Files", "Profile"},
+		{"term", "Terminal", "Alacritty Terminal Emulator"},
+		{"vsc", "Visual Studio Code", "Vast Music"},
+		{"ff", "FireFox", "Fluffy"},
+	}
+	for _, tt := range tests {
+		b, okB := fuzzyScore(tt.pattern, tt.better)
+		w, okW := fuzzyScore(tt.pattern, tt.worse)
+		if !okB || !okW || b <= w {
+			t.Errorf("%q: %q scored %d (%v), %q scored %d (%v)", tt.pattern, tt.better, b, okB, tt.worse, w, okW)
+		}
+	}
+}
+
+func TestSearchAppsMatchesNamesOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	app := func(name, generic, comment, exec string, keywords ...string) desktopApp {
+		path := "/usr/share/applications/" + name + ".desktop"
+		return desktopApp{path: path, entry: &desktopEntry{
+			File: path, ID: name + ".desktop", Type: "Application",
+			Name: name, GenericName: generic, Comment: comment, Exec: exec, Keywords: keywords,
+		}}
+	}
+	apps := []desktopApp{
+		app("Calculator", "Calculator", "Perform arithmetic, scientific or financial calculations", "gnome-calculator"),
+		app("Firefox", "Web Browser", "Browse the World Wide Web", "firefox %u", "Internet", "WWW"),
+		app("Visual Studio Code", "Text Editor", "Code Editing. Redefined.", "code %F", "vscode"),
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"top", nil},
+		{"ls", nil},
+		{"ce", []string{"Visual Studio Code"}},
+		{"ffx", []string{"Firefox"}},
+		{"browser", []string{"Firefox"}},
+		{"www", []string{"Firefox"}},
+		{"redefined", []string{"Visual Studio Code"}}, // a substring of the comment
+		{"calc", []string{"Calculator"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range searchApps(tt.term, apps) {
+			got = append(got, r.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("searchApps(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package search
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hoppxi/wigo/pkg/icontheme"
+)
+
+// rootCheckInterval is how often the index looks for application
+// directories that moved, such as Nix profiles switching generation, which
+// inotify cannot see.
+const rootCheckInterval = 30 * time.Second
+
+// AppIndex holds every parsed .desktop file in memory and keeps it fresh
+// with inotify, so searches do not scan the disk.
+type AppIndex struct {
+	mu    sync.RWMutex
+	apps  map[string]desktopApp
+	list  []desktopApp // apps in lookup order, built on demand
+	roots []string     // applicationDirs with symlinks resolved
+
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func NewAppIndex() *AppIndex {
+	return &AppIndex{apps: make(map[string]desktopApp), ready: make(chan struct{})}
+}
+
+// Run builds the index and keeps it up to date until stop is closed.
+func (x *AppIndex) Run(stop <-chan struct{}) error {
+	defer x.readyOnce.Do(func() { close(x.ready) })
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		x.rebuild(nil)
+		return err
+	}
+	defer w.Close()
+
+	start := time.Now()
+	x.rebuild(w)
+	x.readyOnce.Do(func() { close(x.ready) })
+	log.Printf("Indexed %d desktop files in %v", x.Len(), time.Since(start).Round(time.Millisecond))
+
+	ticker := time.NewTicker(rootCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			x.handle(w, ev)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("app index: %v", err)
+		case <-ticker.C:
+			if x.rootsMoved() {
+				x.rebuild(w)
+			}
+		}
+	}
+}
+
+// Search matches term against the indexed apps, waiting for the first build.
+func (x *AppIndex) Search(term string) []Result {
+	<-x.ready
+
+	x.mu.Lock()
+	if x.list == nil {
+		x.list = make([]desktopApp, 0, len(x.apps))
+		for _, app := range x.apps {
+			x.list = append(x.list, app)
+		}
+		slices.SortFunc(x.list, compareApps)
+	}
+	list := x.list
+	x.mu.Unlock()
+
+	return searchApps(term, list)
+}
+
+// Len returns the number of indexed .desktop files.
+func (x *AppIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.apps)
+}
+
+// rebuild scans everything again and watches the application directories
+// and their subdirectories. The Nix store is scanned but not watched, its
+// packages show up through the profiles.
+func (x *AppIndex) rebuild(w *fsnotify.Watcher) {
+	dirs := applicationDirs()
+	roots := make([]string, len(dirs))
+	apps := map[string]desktopApp{}
+	watch := map[string]bool{}
+
+	for i, d := range dirs {
+		real, err := filepath.EvalSymlinks(d)
+		if err != nil {
+			continue
+		}
+		roots[i] = real
+		filepath.WalkDir(real, func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if de.IsDir() {
+				watch[path] = true
+				return nil
+			}
+			if _, dup := apps[path]; !dup && strings.HasSuffix(path, ".desktop") {
+				if e := parseDesktopEntry(path); e != nil {
+					e.ID = desktopFileID(path, []string{real})
+					apps[path] = desktopApp{path: path, root: i, entry: e}
+				}
+			}
+			return nil
+		})
+	}
+	for _, path := range walkDesktopFiles(storeApplicationDirs()) {
+		if _, dup := apps[path]; !dup {
+			if e := parseDesktopEntry(path); e != nil {
+				apps[path] = desktopApp{path: path, root: len(dirs), entry: e}
+			}
+		}
+	}
+
+	if w != nil {
+		for _, dir := range w.WatchList() {
+			if !watch[dir] {
+				w.Remove(dir)
+			}
+		}
+		for dir := range watch {
+			w.Add(dir)
+		}
+	}
+
+	icontheme.Default().Flush()
+
+	x.mu.Lock()
+	x.apps = apps
+	x.list = nil
+	x.roots = roots
+	x.mu.Unlock()
+}
+
+// rootsMoved tells whether an application directory now resolves somewhere
+// else, or appeared or went away.
+func (x *AppIndex) rootsMoved() bool {
+	dirs := applicationDirs()
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if len(dirs) != len(x.roots) {
+		return true
+	}
+	for i, d := range dirs {
+		real, _ := filepath.EvalSymlinks(d)
+		if real != x.roots[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *AppIndex) handle(w *fsnotify.Watcher, ev fsnotify.Event) {
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			x.addDir(w, ev.Name)
+			return
+		}
+	}
+
+	gone := ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)
+	if !strings.HasSuffix(ev.Name, ".desktop") {
+		if gone {
+			x.removeDir(ev.Name)
+		}
+		return
+	}
+
+	var e *desktopEntry
+	if !gone {
+		e = parseDesktopEntry(ev.Name)
+	}
+
+	// The app may have brought icons the theme cache has not seen
+	icontheme.Default().Flush()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if e == nil {
+		delete(x.apps, ev.Name)
+	} else {
+		e.ID = desktopFileID(ev.Name, x.roots)
+		x.apps[ev.Name] = desktopApp{path: ev.Name, root: x.rootOf(ev.Name), entry: e}
+	}
+	x.list = nil
+}
+
+// addDir watches a new directory and indexes what is already in it.
+func (x *AppIndex) addDir(w *fsnotify.Watcher, dir string) {
+	filepath.WalkDir(dir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if de.IsDir() {
+			w.Add(path)
+			return nil
+		}
+		if strings.HasSuffix(path, ".desktop") {
+			x.handle(w, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+		return nil
+	})
+}
+
+func (x *AppIndex) removeDir(dir string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for path := range x.apps {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			delete(x.apps, path)
+			x.list = nil
+		}
+	}
+}
+
+// rootOf returns the position of the application directory holding path.
+// The caller holds x.mu.
+func (x *AppIndex) rootOf(path string) int {
+	return rootIndex(x.roots, path)
+}
@@ -0,0 +1,545 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/utils"
+)
+
+// launchRecord is one line of the launch log.
+type launchRecord struct {
+	Time    int64    `json:"time"`
+	ID      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	Type    string   `json:"type"`
+	Argv    []string `json:"argv,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+	PID     int      `json:"pid,omitempty"`
+	DBus    bool     `json:"dbus,omitempty"`
+	WMClass string   `json:"wm_class,omitempty"`
+	Focused bool     `json:"focused,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func launchLogPath() string {
+	return filepath.Join(homeDir(), ".local", "share", "wigo", "launch.log")
+}
+
+// launchToken encodes what launching r takes as base64url, which is safe
+// to paste into a shell command unquoted. Tokens stand on their own, so a
+// launch does not depend on which search ran last.
+func launchToken(r Result) string {
+	data, _ := json.Marshal(Result{ID: r.ID, Name: r.Name, Type: r.Type, Source: r.Source, Command: r.Command})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// resolveLaunch turns a launch token or a result ID into the result to run.
+// IDs always hold a colon, tokens never do. Apps, binaries and files can be
+// launched by ID; everything else needs its token.
+func resolveLaunch(arg string) (Result, error) {
+	if !strings.Contains(arg, ":") {
+		var r Result
+		data, err := base64.RawURLEncoding.DecodeString(arg)
+		if err != nil || json.Unmarshal(data, &r) != nil || r.ID == "" {
+			return r, fmt.Errorf("invalid launch token %q", arg)
+		}
+		return r, nil
+	}
+
+	kind, name, _ := strings.Cut(arg, ":")
+	switch kind {
+	case "app":
+		desktopID, _ := splitAppID(name)
+		path := findDesktopFile(desktopID)
+		if path == "" {
+			return Result{}, fmt.Errorf("no desktop entry %s", desktopID)
+		}
+		return Result{ID: arg, Type: "app", Source: path}, nil
+	case "bin":
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{ID: arg, Name: name, Type: "bin", Source: path}, nil
+	case "file":
+		return Result{ID: arg, Name: filepath.Base(name), Type: "file", Source: name}, nil
+	}
+	return Result{}, fmt.Errorf("%s results are launched by the launch token of `wigo search`", kind)
+}
+
+// Launch runs a search result, given by its launch token or ID, passing
+// files or URLs to apps that take them. App IDs are "app:firefox.desktop",
+// or "app:firefox.desktop:new-window" for an action. terminal is the
+// `apps.terminal` command. Launches are detached, counted for frecency and
+// logged.
+func Launch(arg, terminal string, files []string) error {
+	r, err := resolveLaunch(arg)
+	if err != nil {
+		return err
+	}
+	id := r.ID
+
+	rec := launchRecord{Time: time.Now().Unix(), ID: id, Name: r.Name, Type: r.Type}
+	err = launchResult(r, terminal, files, &rec)
+	if err != nil {
+		rec.Error = err.Error()
+	} else {
+		RecordLaunch(id)
+	}
+	logLaunch(rec)
+	return err
+}
+
+func launchResult(r Result, terminal string, files []string, rec *launchRecord) error {
+	switch r.Type {
+	case "app":
+		_, action := splitAppID(strings.TrimPrefix(r.ID, "app:"))
+		return launchDesktopFile(r.Source, action, terminal, files, rec)
+	case "bin":
+		return start(inTerminal(terminal, []string{r.Source}), "", nil, rec)
+	case "file":
+		return start([]string{"xdg-open", r.Source}, "", nil, rec)
+	case "help":
+		return errors.New("help entries cannot be launched")
+	}
+	if r.Command == "" {
+		return errors.New("nothing to launch")
+	}
+	// Extensions and modes hand out shell commands
+	return start([]string{"sh", "-c", r.Command}, "", nil, rec)
+}
+
+// splitAppID splits "firefox.desktop:new-window" into the desktop file ID
+// and the action.
+func splitAppID(id string) (desktopID, action string) {
+	if desktopID, action, ok := strings.Cut(id, ".desktop:"); ok {
+		return desktopID + ".desktop", action
+	}
+	return id, ""
+}
+
+// launchDesktopFile starts the app of path, or one of its actions. Under
+// Hyprland, the window the entry's StartupWMClass names is focused once it
+// maps.
+func launchDesktopFile(path, actionID, terminal string, files []string, rec *launchRecord) error {
+	e := parseDesktopEntry(path)
+	if e == nil {
+		return fmt.Errorf("cannot read %s", path)
+	}
+	if rec.Name == "" {
+		rec.Name = e.Name
+	}
+	rec.WMClass = e.StartupWMClass
+
+	var before map[string]bool
+	if e.StartupWMClass != "" {
+		before, _ = windowsOfClass(e.StartupWMClass)
+	}
+	if err := runDesktopEntry(e, path, actionID, terminal, files, rec); err != nil {
+		return err
+	}
+	if before != nil {
+		rec.Focused = focusNewWindow(e.StartupWMClass, before, focusTimeout)
+	}
+	return nil
+}
+
+func runDesktopEntry(e *desktopEntry, path, actionID, terminal string, files []string, rec *launchRecord) error {
+	execLine := e.Exec
+	if actionID != "" {
+		a, ok := e.action(actionID)
+		if !ok {
+			return fmt.Errorf("%s has no action %s", e.ID, actionID)
+		}
+		execLine = a.Exec
+	}
+
+	if e.DBusActivatable {
+		if err := activateDBus(strings.TrimSuffix(e.ID, ".desktop"), actionID, files); err == nil {
+			rec.DBus = true
+			return nil
+		}
+		// Not running as a service after all, fall back to Exec
+	}
+
+	args, err := parseExec(execLine)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	env := []string{"GIO_LAUNCHED_DESKTOP_FILE=" + path}
+	for _, argv := range expandExec(args, e, files) {
+		if e.Terminal {
+			argv = inTerminal(terminal, argv)
+		}
+		if err := start(argv, expandHome(e.Path), env, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// focusTimeout is how long a launch waits for the window of its
+// StartupWMClass.
+var focusTimeout = 5 * time.Second
+
+type hyprClient struct {
+	Address      string `json:"address"`
+	Class        string `json:"class"`
+	InitialClass string `json:"initialClass"`
+}
+
+// windowsOfClass returns the addresses of the Hyprland windows of class.
+// It fails when Hyprland is not running.
+func windowsOfClass(class string) (map[string]bool, error) {
+	data, err := utils.HyprQuery("j/clients")
+	if err != nil {
+		return nil, err
+	}
+	var clients []hyprClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool)
+	for _, c := range clients {
+		if strings.EqualFold(c.Class, class) || strings.EqualFold(c.InitialClass, class) {
+			out[c.Address] = true
+		}
+	}
+	return out, nil
+}
+
+// focusNewWindow waits for a window of class that is not in before and
+// focuses it. Apps that reuse a running instance map no new window, so
+// a window that was already there is focused when the wait runs out.
+func focusNewWindow(class string, before map[string]bool, timeout time.Duration) bool {
+	var existing string
+	for deadline := time.Now().Add(timeout); ; {
+		windows, err := windowsOfClass(class)
+		if err != nil {
+			return false
+		}
+		for addr := range windows {
+			if !before[addr] {
+				return utils.HyprCmd("dispatch focuswindow address:"+addr) == nil
+			}
+			existing = addr
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if existing == "" {
+		return false
+	}
+	return utils.HyprCmd("dispatch focuswindow address:"+existing) == nil
+}
+
+// inTerminal runs argv in the terminal of `apps.terminal`. The command
+// follows -e, unless the terminal command ends in a flag of its own, such
+// as "kitty --" or "wezterm start --".
+func inTerminal(terminal string, argv []string) []string {
+	term := shellSplit(terminal)
+	if len(term) == 0 {
+		term = []string{"xterm"}
+	}
+	if !strings.HasPrefix(term[len(term)-1], "-") {
+		term = append(term, "-e")
+	}
+	return append(term, argv...)
+}
+
+// start runs argv detached in its own session and process group, so it
+// outlives wigo and the widget that asked for it.
+func start(argv []string, dir string, env []string, rec *launchRecord) error {
+	if len(argv) == 0 {
+		return errors.New("empty command")
+	}
+	rec.Argv, rec.Dir = argv, dir
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	rec.PID = cmd.Process.Pid
+	return cmd.Process.Release()
+}
+
+// activateDBus starts a DBusActivatable app, or one of its actions, through
+// org.freedesktop.Application.
+func activateDBus(appID, action string, files []string) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	platform := map[string]dbus.Variant{}
+	if token := os.Getenv("XDG_ACTIVATION_TOKEN"); token != "" {
+		platform["activation-token"] = dbus.MakeVariant(token)
+		platform["desktop-startup-id"] = dbus.MakeVariant(token)
+	}
+
+	objPath := "/" + strings.NewReplacer(".", "/", "-", "_").Replace(appID)
+	obj := conn.Object(appID, dbus.ObjectPath(objPath))
+	if action != "" {
+		return obj.Call("org.freedesktop.Application.ActivateAction", 0, action, []dbus.Variant{}, platform).Err
+	}
+	if len(files) > 0 {
+		uris := make([]string, len(files))
+		for i, f := range files {
+			uris[i] = toURI(f)
+		}
+		return obj.Call("org.freedesktop.Application.Open", 0, uris, platform).Err
+	}
+	return obj.Call("org.freedesktop.Application.Activate", 0, platform).Err
+}
+
+// findDesktopFile looks a desktop file ID up in the application
+// directories. The dashes of an ID may stand for subdirectories, so
+// kde4-foo.desktop is also looked for as kde4/foo.desktop.
+func findDesktopFile(id string) string {
+	if !strings.HasSuffix(id, ".desktop") {
+		id += ".desktop"
+	}
+	for _, dir := range applicationDirs() {
+		if path := findIn(dir, id); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// findIn tries id in dir, then each dash of it as a subdirectory.
+func findIn(dir, id string) string {
+	if path := filepath.Join(dir, id); exists(path) {
+		return path
+	}
+	for i := range len(id) {
+		if id[i] != '-' {
+			continue
+		}
+		sub := filepath.Join(dir, id[:i])
+		if fi, err := os.Stat(sub); err == nil && fi.IsDir() {
+			if path := findIn(sub, id[i+1:]); path != "" {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func logLaunch(rec launchRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	path := launchLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// parseExec splits an Exec value into arguments by the quoting rules of
+// the Desktop Entry spec: the string escapes (\s, \n, \t, \r, \\) first,
+// then double quoted arguments in which \", \`, \$ and \\ are escaped.
+func parseExec(value string) ([]string, error) {
+	value = unescapeString(value)
+
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		inQuote bool
+	)
+	rs := []rune(value)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case inQuote && r == '\\' && i+1 < len(rs) && strings.ContainsRune("\"`$\\", rs[i+1]):
+			i++
+			cur.WriteRune(rs[i])
+		case r == '"':
+			inQuote = !inQuote
+			inArg = true
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inQuote {
+		return nil, errors.New("unterminated quote in Exec")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty Exec")
+	}
+	return args, nil
+}
+
+func unescapeString(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			// Left for the Exec quoting rules
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// expandExec replaces the field codes of args. %f and %u take one file,
+// also inside a larger argument such as --file=%f, so several files start
+// one instance each; %F and %U take them all and only stand alone.
+// Codes without a value are removed, deprecated ones dropped.
+func expandExec(args []string, e *desktopEntry, files []string) [][]string {
+	single := false
+	for _, a := range args {
+		if hasFieldCode(a, 'f') || hasFieldCode(a, 'u') {
+			single = true
+		}
+	}
+	if single && len(files) > 1 {
+		var out [][]string
+		for _, f := range files {
+			out = append(out, expandArgs(args, e, []string{f}))
+		}
+		return out
+	}
+	return [][]string{expandArgs(args, e, files)}
+}
+
+// expandArgs expands the field codes for one instance; e may be nil when
+// only the codes are to be removed.
+func expandArgs(args []string, e *desktopEntry, files []string) []string {
+	if e == nil {
+		e = &desktopEntry{}
+	}
+	var out []string
+	for _, a := range args {
+		switch a {
+		case "%F", "%U":
+			for _, f := range files {
+				if a == "%U" {
+					f = toURI(f)
+				}
+				out = append(out, f)
+			}
+			continue
+		case "%f", "%u":
+			if len(files) > 0 {
+				f := files[0]
+				if a == "%u" {
+					f = toURI(f)
+				}
+				out = append(out, f)
+			}
+			continue
+		case "%i":
+			if e.Icon != "" {
+				out = append(out, "--icon", e.Icon)
+			}
+			continue
+		}
+
+		var b strings.Builder
+		for i := 0; i < len(a); i++ {
+			if a[i] != '%' || i+1 == len(a) {
+				b.WriteByte(a[i])
+				continue
+			}
+			i++
+			switch a[i] {
+			case '%':
+				b.WriteByte('%')
+			case 'c':
+				b.WriteString(e.Name)
+			case 'k':
+				b.WriteString(e.File)
+			case 'f':
+				if len(files) > 0 {
+					b.WriteString(files[0])
+				}
+			case 'u':
+				if len(files) > 0 {
+					b.WriteString(toURI(files[0]))
+				}
+			}
+		}
+		if s := b.String(); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// hasFieldCode reports whether a holds %code, which %% escapes.
+func hasFieldCode(a string, code byte) bool {
+	for i := 0; i+1 < len(a); i++ {
+		if a[i] == '%' {
+			if a[i+1] == code {
+				return true
+			}
+			i++
+		}
+	}
+	return false
+}
+
+// toURI turns local paths into file URIs and leaves URIs alone.
+func toURI(f string) string {
+	if strings.Contains(f, "://") {
+		return f
+	}
+	if abs, err := filepath.Abs(f); err == nil {
+		f = abs
+	}
+	return "file://" + f
+}
@@ -0,0 +1,250 @@
+package search
+
+import (
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hoppxi/wigo/internal/utils"
+)
+
+func TestParseExec(t *testing.T) {
+	tests := []struct {
+		exec string
+		want []string
+		err  bool
+	}{
+		{`firefox %u`, []string{"firefox", "%u"}, false},
+		{`  spaced   out  `, []string{"spaced", "out"}, false},
+		{`"/opt/My App/run" --flag`, []string{"/opt/My App/run", "--flag"}, false},
+		{`sh -c "echo \"hi\" \$HOME \` + "`" + `x\` + "`" + `"`, []string{"sh", "-c", "echo \"hi\" $HOME `x`"}, false},
+		// \\\\ in the file is \\ after the string escapes, one \ after quoting
+		{`sh -c "a \\\\ b"`, []string{"sh", "-c", `a \ b`}, false},
+		{`foo\sbar`, []string{"foo", "bar"}, false},
+		{`"" empty`, []string{"", "empty"}, false},
+		{`a"b c"d`, []string{"ab cd"}, false},
+		{`"unterminated`, nil, true},
+		{`   `, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseExec(tt.exec)
+		if (err != nil) != tt.err || !slices.Equal(got, tt.want) {
+			t.Errorf("parseExec(%q) = %q, %v; want %q, err %v", tt.exec, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestUnescapeString(t *testing.T) {
+	tests := map[string]string{
+		`plain`:     "plain",
+		`a\sb`:      "a b",
+		`l1\nl2`:    "l1\nl2",
+		`\t\r`:      "\t\r",
+		`back\\`:    `back\`,
+		`keep\"`:    `keep\"`,
+		`trailing\`: `trailing\`,
+	}
+	for in, want := range tests {
+		if got := unescapeString(in); got != want {
+			t.Errorf("unescapeString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExpandExec(t *testing.T) {
+	e := &desktopEntry{File: "/usr/share/applications/app.desktop", Name: "My App", Icon: "app-icon"}
+	files := []string{"/tmp/a b.txt", "https://example.com/x"}
+
+	tests := []struct {
+		exec  string
+		files []string
+		want  [][]string
+	}{
+		{`app %F`, files, [][]string{{"app", "/tmp/a b.txt", "https://example.com/x"}}},
+		{`app %U`, files, [][]string{{"app", "file:///tmp/a b.txt", "https://example.com/x"}}},
+		// %f takes one file, so two files start two instances
+		{`app %f`, files, [][]string{{"app", "/tmp/a b.txt"}, {"app", "https://example.com/x"}}},
+		{`app %u`, files, [][]string{{"app", "file:///tmp/a b.txt"}, {"app", "https://example.com/x"}}},
+		{`app %f`, nil, [][]string{{"app"}}},
+		{`app %F --end`, nil, [][]string{{"app", "--end"}}},
+		{`app %i %c %k`, nil, [][]string{{"app", "--icon", "app-icon", "My App", "/usr/share/applications/app.desktop"}}},
+		{`app --file=%f`, []string{"/x"}, [][]string{{"app", "--file=/x"}}},
+		{`app --url=%u`, []string{"/x"}, [][]string{{"app", "--url=file:///x"}}},
+		{`app --url=%u`, files, [][]string{{"app", "--url=file:///tmp/a b.txt"}, {"app", "--url=https://example.com/x"}}},
+		{`app --file=%F`, files, [][]string{{"app", "--file="}}},
+		{`app 100%%f %F`, files, [][]string{{"app", "100%f", "/tmp/a b.txt", "https://example.com/x"}}},
+		{`app 100%% %d %D %n %N %v %m`, nil, [][]string{{"app", "100%"}}},
+	}
+	for _, tt := range tests {
+		args, err := parseExec(tt.exec)
+		if err != nil {
+			t.Fatalf("parseExec(%q): %v", tt.exec, err)
+		}
+		got := expandExec(args, e, tt.files)
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("expandExec(%q, %q) = %q, want %q", tt.exec, tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestExpandArgsWithoutEntry(t *testing.T) {
+	got := expandArgs([]string{"app", "%i", "%c", "%U"}, nil, nil)
+	if !slices.Equal(got, []string{"app"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSanitizeExecField(t *testing.T) {
+	tests := map[string]string{
+		`firefox %u`:                        "firefox",
+		`code --new-window %F`:              "code --new-window",
+		`"/opt/My App/run" --name "a b" %U`: `'/opt/My App/run' --name 'a b'`,
+	}
+	for in, want := range tests {
+		if got := sanitizeExecField(in); got != want {
+			t.Errorf("sanitizeExecField(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestInTerminal(t *testing.T) {
+	tests := []struct {
+		terminal string
+		want     []string
+	}{
+		{"alacritty", []string{"alacritty", "-e", "htop"}},
+		{"wezterm start --", []string{"wezterm", "start", "--", "htop"}},
+		{"kitty --", []string{"kitty", "--", "htop"}},
+		{"", []string{"xterm", "-e", "htop"}},
+	}
+	for _, tt := range tests {
+		if got := inTerminal(tt.terminal, []string{"htop"}); !slices.Equal(got, tt.want) {
+			t.Errorf("inTerminal(%q) = %q, want %q", tt.terminal, got, tt.want)
+		}
+	}
+}
+
+func TestLaunchToken(t *testing.T) {
+	r := Result{ID: "file:/tmp/x'; rm -rf ~; '", Name: "x", Type: "file", Source: "/tmp/x'; rm -rf ~; '", Icon: "dropped"}
+	token := launchToken(r)
+	for _, c := range token {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			t.Fatalf("token %q holds %q", token, c)
+		}
+	}
+
+	got, err := resolveLaunch(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != r.ID || got.Source != r.Source || got.Type != r.Type || got.Icon != "" {
+		t.Errorf("resolveLaunch(token) = %+v", got)
+	}
+
+	if _, err := resolveLaunch("not a token!"); err == nil {
+		t.Error("resolveLaunch accepted garbage")
+	}
+	if _, err := resolveLaunch("emoji:x"); err == nil {
+		t.Error("emoji results cannot be launched by id")
+	}
+	if got, err := resolveLaunch("file:/etc/hosts"); err != nil || got.Source != "/etc/hosts" {
+		t.Errorf("resolveLaunch(file id) = %+v, %v", got, err)
+	}
+}
+
+func TestSplitAppID(t *testing.T) {
+	tests := []struct{ in, id, action string }{
+		{"firefox.desktop", "firefox.desktop", ""},
+		{"firefox.desktop:new-private-window", "firefox.desktop", "new-private-window"},
+		{"org.gnome.Nautilus.desktop:new-window", "org.gnome.Nautilus.desktop", "new-window"},
+	}
+	for _, tt := range tests {
+		if id, action := splitAppID(tt.in); id != tt.id || action != tt.action {
+			t.Errorf("splitAppID(%q) = %q, %q", tt.in, id, action)
+		}
+	}
+}
+
+// fakeHyprland serves j/clients from clients and records dispatches.
+func fakeHyprland(t *testing.T, clients func() string) <-chan string {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+	if err := os.MkdirAll(filepath.Dir(utils.HyprctlSocket()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", utils.HyprctlSocket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	dispatched := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 256)
+			n, _ := conn.Read(buf)
+			if cmd := string(buf[:n]); cmd == "j/clients" {
+				io.WriteString(conn, clients())
+			} else {
+				dispatched <- cmd
+			}
+			conn.Close()
+		}
+	}()
+	return dispatched
+}
+
+func TestFocusNewWindow(t *testing.T) {
+	var polls atomic.Int32
+	dispatched := fakeHyprland(t, func() string {
+		if polls.Add(1) < 3 {
+			return `[{"address":"0x1","class":"code"},{"address":"0x2","class":"kitty"}]`
+		}
+		return `[{"address":"0x1","class":"code"},{"address":"0x3","class":"other","initialClass":"Code"}]`
+	})
+
+	before, err := windowsOfClass("Code")
+	if err != nil || !maps.Equal(before, map[string]bool{"0x1": true}) {
+		t.Fatalf("windowsOfClass = %v, %v", before, err)
+	}
+	if !focusNewWindow("Code", before, 5*time.Second) {
+		t.Fatal("not focused")
+	}
+	if cmd := <-dispatched; cmd != "dispatch focuswindow address:0x3" {
+		t.Errorf("dispatched %q", cmd)
+	}
+}
+
+func TestFocusExistingWindow(t *testing.T) {
+	dispatched := fakeHyprland(t, func() string {
+		return `[{"address":"0x1","class":"firefox"}]`
+	})
+
+	if !focusNewWindow("firefox", map[string]bool{"0x1": true}, 200*time.Millisecond) {
+		t.Fatal("not focused")
+	}
+	if cmd := <-dispatched; cmd != "dispatch focuswindow address:0x1" {
+		t.Errorf("dispatched %q", cmd)
+	}
+	if focusNewWindow("nothing", nil, 0) {
+		t.Error("focused a window of a class nobody has")
+	}
+}
+
+func TestFocusWithoutHyprland(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	if _, err := windowsOfClass("code"); err == nil {
+		t.Error("windowsOfClass did not fail without Hyprland")
+	}
+}
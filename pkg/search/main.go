@@ -2,13 +2,14 @@ package search
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Result struct {
+	// ID names the result across searches, for frecency; see RecordLaunch.
+	ID      string `json:"id,omitempty"`
 	Name    string `json:"name"`
 	GUI     bool   `json:"gui"`
 	Type    string `json:"type"`
@@ -16,6 +17,10 @@ type Result struct {
 	Command string `json:"command"`
 	Icon    string `json:"icon,omitempty"`
 	Comment string `json:"comment,omitempty"`
+	// Launch is the shell-safe argument of `wigo launch` for the result.
+	Launch string `json:"launch,omitempty"`
+
+	score int
 }
 
 type ExtConfig struct {
@@ -30,8 +35,6 @@ type ExtConfig struct {
 	Limit        int    `mapstructure:"limit"`
 }
 
-var placeholderRe = regexp.MustCompile(`%[fFuUdDnNickvm]`)
-
 func Search(args []string, v *viper.Viper, configKey string) {
 
 	term := ""
@@ -204,7 +207,7 @@ func helpJSON(term string, extensions []ExtConfig) []Result {
 }
 
 func defaultSearch(term string) []Result {
-	apps := SearchDesktopApps(term)
+	apps := Apps(term)
 	if len(apps) > 0 {
 		return apps
 	}
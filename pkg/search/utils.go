@@ -10,8 +10,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // Tokenize input into numbers, operators, parentheses
@@ -296,17 +298,20 @@ func isExecutable(p string) bool {
 	return !mode.IsDir() && mode&0111 != 0
 }
 
+// sanitizeExecField turns an Exec value into a shell command line without
+// its field codes, keeping the arguments.
 func sanitizeExecField(execLine string) string {
-	if execLine == "" {
-		return ""
+	args, err := parseExec(execLine)
+	if err != nil {
+		return strings.TrimSpace(execLine)
 	}
-	execLine = placeholderRe.ReplaceAllString(execLine, "")
-	execLine = strings.TrimSpace(execLine)
-	parts := shellSplit(execLine)
-	if len(parts) == 0 {
-		return execLine
+	args = expandArgs(args, nil, nil)
+	for i, a := range args {
+		if strings.ContainsAny(a, " \t\n'\"\\$`|&;<>()*?[]#~") {
+			args[i] = shellEscape(a)
+		}
 	}
-	return parts[0]
+	return strings.Join(args, " ")
 }
 
 func exists(path string) bool {
@@ -358,30 +363,62 @@ func tokensFrom(s string) []string {
 	return out
 }
 
+// printJSON prints the results with their launch tokens; results without
+// an ID get one from their type and name.
 func printJSON(arr []Result) {
+	for i, r := range arr {
+		if r.ID == "" {
+			arr[i].ID = r.Type + ":" + r.Name
+		}
+		arr[i].Launch = launchToken(arr[i])
+	}
 	enc, _ := json.MarshalIndent(arr, "", "  ")
 	fmt.Println(string(enc))
 }
 
-// matchScore returns (matched,score) where higher score is better
-// simple algorithm: require all tokens to appear (AND); score is -sum(positions) so earlier positions -> higher score
+// matchScore fuzzy matches every token against the fields, see fuzzyScore,
+// and sums the best score of each. All tokens must match. The first field
+// counts fully, the others half.
 func matchScore(tokens []string, fields ...string) (bool, int) {
-	if len(tokens) == 0 {
-		return true, 0
-	}
-	combined := strings.ToLower(strings.Join(fields, " "))
-	score := 0
+	return matchFields(tokens, fields, nil)
+}
+
+// matchFields is matchScore over the fuzzy fields, where a token may also
+// be a plain substring of one of the text fields, such as descriptions, for
+// a score of 0.
+func matchFields(tokens []string, fuzzy []string, text []string) (bool, int) {
+	total := 0
 	for _, t := range tokens {
-		if t == "" {
-			continue
+		best, found := 0, false
+		for i, f := range fuzzy {
+			s, ok := fuzzyScore(t, f)
+			if !ok {
+				continue
+			}
+			if i > 0 {
+				s /= 2
+			}
+			if !found || s > best {
+				best, found = s, true
+			}
 		}
-		idx := strings.Index(combined, strings.ToLower(t))
-		if idx == -1 {
+		if !found && !slices.ContainsFunc(text, func(f string) bool { return containsAll([]string{t}, f) }) {
 			return false, 0
 		}
-		score += (100000 - idx) // earlier -> higher contribution
+		total += best
 	}
-	return true, score
+	return true, total
+}
+
+// containsAll tells whether text holds every token, ignoring case.
+func containsAll(tokens []string, text string) bool {
+	text = strings.ToLower(text)
+	for _, t := range tokens {
+		if !strings.Contains(text, strings.ToLower(t)) {
+			return false
+		}
+	}
+	return true
 }
 
 func expandHome(path string) string {
@@ -397,3 +434,23 @@ func expandHome(path string) string {
 	}
 	return path
 }
+
+// lockFile takes an exclusive flock on name, waiting for other processes
+// holding it, and returns the function releasing it.
+func lockFile(name string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -96,6 +96,27 @@ func GetActiveWindow() Window {
 	}
 }
 
+// IsActiveFullscreen reports whether the focused window is fullscreen.
+// Hyprland reports this as a bool on older releases and as a mode on newer
+// ones, where 1 is maximized and 2 fullscreen.
+func IsActiveFullscreen() bool {
+	var data struct {
+		Fullscreen any `json:"fullscreen"`
+	}
+	res, err := hyprQuery("j/activewindow")
+	if err != nil || json.Unmarshal(res, &data) != nil {
+		return false
+	}
+
+	switch v := data.Fullscreen.(type) {
+	case bool:
+		return v
+	case float64:
+		return v >= 2
+	}
+	return false
+}
+
 func GetWorkspaces() []Workspace {
 	persistent := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 	workspaceMap := make(map[int][]Window)
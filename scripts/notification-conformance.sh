@@ -0,0 +1,21 @@
+#!/usr/bin/env bash
+
+# Runs the notification spec checks on a private session bus, with a
+# throwaway HOME so history and the image cache stay untouched.
+
+set -euo pipefail
+
+if ! command -v dbus-run-session >/dev/null 2>&1; then
+  echo "Error: dbus-run-session is not installed or not in PATH"
+  exit 1
+fi
+
+cd "$(dirname "$0")/.."
+
+TMP_HOME="$(mktemp -d)"
+trap 'rm -rf "${TMP_HOME}"' EXIT
+
+go build -o "${TMP_HOME}/notification-conformance" ./scripts/notification-conformance
+
+HOME="${TMP_HOME}" WIGO_CONFORMANCE=1 \
+  dbus-run-session -- "${TMP_HOME}/notification-conformance"
@@ -0,0 +1,367 @@
+// Command notification-conformance checks the notification daemon against
+// the freedesktop notification spec and the org.wigo.Notifications extension.
+// It serves the daemon itself and talks to it as a client, so it must run on a
+// private session bus; use scripts/notification-conformance.sh.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/hoppxi/wigo/internal/sink"
+	"github.com/hoppxi/wigo/internal/watchers"
+)
+
+const (
+	busName = "org.freedesktop.Notifications"
+	objPath = "/org/freedesktop/Notifications"
+
+	wigoName = "org.wigo.Notifications"
+	wigoPath = "/org/wigo/Notifications"
+)
+
+var (
+	obj      dbus.BusObject
+	wigo     dbus.BusObject
+	signals  = make(chan *dbus.Signal, 64)
+	pending  []*dbus.Signal
+	failures int
+)
+
+func main() {
+	if os.Getenv("WIGO_CONFORMANCE") == "" {
+		fmt.Println("Run through scripts/notification-conformance.sh, it needs a private session bus.")
+		os.Exit(2)
+	}
+
+	// Nothing to render to
+	sink.Default = sink.NewMulti()
+
+	stop := make(chan struct{})
+	go func() {
+		if err := watchers.StartNotificationService(stop); err != nil {
+			fmt.Println("notification service:", err)
+			os.Exit(1)
+		}
+	}()
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		fmt.Println("session bus:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := waitForName(conn); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	conn.AddMatchSignal(dbus.WithMatchInterface(busName))
+	conn.Signal(signals)
+	obj = conn.Object(busName, objPath)
+	wigo = conn.Object(wigoName, wigoPath)
+
+	serverInformation()
+	capabilities()
+	monotonicIDs()
+	replacesID()
+	synchronous()
+	closeNotification()
+	expiry()
+	resident()
+	transient()
+	imageData()
+	control()
+	replies()
+
+	close(stop)
+	if failures > 0 {
+		fmt.Printf("%d checks failed\n", failures)
+		os.Exit(1)
+	}
+	fmt.Println("all checks passed")
+}
+
+func waitForName(conn *dbus.Conn) error {
+	for range 50 {
+		var owned bool
+		conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, busName).Store(&owned)
+		if owned {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("%s did not appear on the bus", busName)
+}
+
+func check(name string, ok bool, format string, args ...any) {
+	if ok {
+		fmt.Println("ok  ", name)
+		return
+	}
+	failures++
+	fmt.Printf("FAIL %s: %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func notify(appName string, replacesID uint32, hints map[string]dbus.Variant, timeout int32) uint32 {
+	if hints == nil {
+		hints = map[string]dbus.Variant{}
+	}
+	var id uint32
+	err := obj.Call(busName+".Notify", 0, appName, replacesID, "", "summary", "body",
+		[]string{"default", "Open"}, hints, timeout).Store(&id)
+	if err != nil {
+		failures++
+		fmt.Println("FAIL Notify:", err)
+	}
+	return id
+}
+
+// waitSignal waits up to d for the signal member about id and returns it,
+// or nil if none came.
+func waitSignal(member string, id uint32, d time.Duration) *dbus.Signal {
+	take := func() *dbus.Signal {
+		for i, sig := range pending {
+			if sig.Name == busName+"."+member && len(sig.Body) > 0 && sig.Body[0] == id {
+				pending = slices.Delete(pending, i, i+1)
+				return sig
+			}
+		}
+		return nil
+	}
+
+	if sig := take(); sig != nil {
+		return sig
+	}
+	timeout := time.After(d)
+	for {
+		select {
+		case sig := <-signals:
+			pending = append(pending, sig)
+			if sig := take(); sig != nil {
+				return sig
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+// waitClosed waits up to d for NotificationClosed of id and returns its
+// reason, or 0 if none came.
+func waitClosed(id uint32, d time.Duration) uint32 {
+	sig := waitSignal("NotificationClosed", id, d)
+	if sig == nil || len(sig.Body) != 2 {
+		return 0
+	}
+	reason, _ := sig.Body[1].(uint32)
+	return reason
+}
+
+func serverInformation() {
+	var name, vendor, version, spec string
+	err := obj.Call(busName+".GetServerInformation", 0).Store(&name, &vendor, &version, &spec)
+	check("GetServerInformation", err == nil && spec == "1.2", "spec %q, err %v", spec, err)
+}
+
+func capabilities() {
+	var caps []string
+	err := obj.Call(busName+".GetCapabilities", 0).Store(&caps)
+	want := []string{"action-icons", "actions", "body", "body-markup", "icon-static", "inline-reply", "persistence", "sound"}
+	ok := err == nil
+	for _, c := range want {
+		ok = ok && slices.Contains(caps, c)
+	}
+	check("GetCapabilities", ok, "got %v, err %v", caps, err)
+}
+
+func monotonicIDs() {
+	a := notify("conformance", 0, nil, 0)
+	b := notify("conformance", 0, nil, 0)
+	c := notify("conformance", 0, nil, 0)
+	check("IDs are non-zero and increasing", a != 0 && a < b && b < c, "got %d, %d, %d", a, b, c)
+}
+
+func replacesID() {
+	a := notify("conformance", 0, nil, 0)
+	b := notify("conformance", a, nil, 0)
+	check("replaces_id keeps the ID", a == b, "replaced %d, got %d", a, b)
+	check("replacing in place does not close", waitClosed(a, 300*time.Millisecond) == 0, "got NotificationClosed")
+
+	c := notify("conformance", 0xfffffff0, nil, 0)
+	check("unknown replaces_id gets a new ID", c != 0 && c != 0xfffffff0, "got %d", c)
+}
+
+func synchronous() {
+	hints := map[string]dbus.Variant{"x-canonical-private-synchronous": dbus.MakeVariant("volume")}
+	a := notify("conformance", 0, hints, 0)
+	b := notify("conformance", 0, hints, 0)
+	check("synchronous notification gets a new ID", b != a, "got %d twice", a)
+	reason := waitClosed(a, time.Second)
+	check("synchronous notification closes the old one", reason == 4, "reason %d", reason)
+}
+
+func closeNotification() {
+	id := notify("conformance", 0, nil, 0)
+	err := obj.Call(busName+".CloseNotification", 0, id).Err
+	reason := waitClosed(id, time.Second)
+	check("CloseNotification", err == nil && reason == 3, "reason %d, err %v", reason, err)
+
+	err = obj.Call(busName+".CloseNotification", 0, id).Err
+	check("CloseNotification of a closed notification fails", err != nil, "no error")
+}
+
+func expiry() {
+	id := notify("conformance", 0, nil, 200)
+	reason := waitClosed(id, 2*time.Second)
+	check("expire_timeout closes as expired", reason == 1, "reason %d", reason)
+
+	id = notify("conformance", 0, nil, 300)
+	time.Sleep(100 * time.Millisecond)
+	notify("conformance", id, nil, 0)
+	check("replacing re-arms the timeout", waitClosed(id, 600*time.Millisecond) == 0, "expired anyway")
+	obj.Call(busName+".CloseNotification", 0, id)
+	waitClosed(id, time.Second)
+}
+
+func resident() {
+	hints := map[string]dbus.Variant{"resident": dbus.MakeVariant(true)}
+	id := notify("conformance", 0, hints, 0)
+	wigo.Call(wigoName+".InvokeAction", 0, id, "default")
+	check("resident notification stays after an action", waitClosed(id, 300*time.Millisecond) == 0, "closed")
+
+	id = notify("conformance", 0, nil, 0)
+	wigo.Call(wigoName+".InvokeAction", 0, id, "default")
+	reason := waitClosed(id, time.Second)
+	check("action closes as dismissed", reason == 2, "reason %d", reason)
+}
+
+func transient() {
+	var before, after uint32
+	wigo.Call(wigoName+".GetHistoryCount", 0).Store(&before)
+	notify("conformance", 0, map[string]dbus.Variant{"transient": dbus.MakeVariant(true)}, 0)
+	wigo.Call(wigoName+".GetHistoryCount", 0).Store(&after)
+	check("transient notification skips history", before == after, "history grew from %d to %d", before, after)
+}
+
+func imageData() {
+	// 1x2 RGB image with one byte of padding per row; the last row has none
+	data := []byte{255, 0, 0, 0xee, 0, 0, 255}
+	img := rawImage(1, 2, 4, 3, data)
+	id := notify("conformance", 0, map[string]dbus.Variant{"image-data": img}, 0)
+
+	file := historyIcon(id)
+	f, err := os.Open(file)
+	if err != nil {
+		check("image-data honors rowstride", false, "%v", err)
+		return
+	}
+	defer f.Close()
+
+	decoded, err := png.Decode(f)
+	if err != nil {
+		check("image-data honors rowstride", false, "%v", err)
+		return
+	}
+	r0, _, b0, _ := decoded.At(0, 0).RGBA()
+	r1, _, b1, _ := decoded.At(0, 1).RGBA()
+	check("image-data honors rowstride", r0 == 0xffff && b0 == 0 && r1 == 0 && b1 == 0xffff,
+		"got %v and %v", decoded.At(0, 0), decoded.At(0, 1))
+
+	again := notify("conformance", 0, map[string]dbus.Variant{"image-data": img}, 0)
+	check("image-data is cached by content", historyIcon(again) == file, "got %s and %s", file, historyIcon(again))
+
+	big := rawImage(600, 300, 600*4, 4, make([]byte, 600*300*4))
+	id = notify("conformance", 0, map[string]dbus.Variant{"image-data": big}, 0)
+	cfg, err := decodeConfig(historyIcon(id))
+	check("large image-data is scaled down", err == nil && cfg.Width == 256 && cfg.Height == 128,
+		"got %dx%d, err %v", cfg.Width, cfg.Height, err)
+}
+
+func rawImage(width, height, rowstride, channels int32, data []byte) dbus.Variant {
+	return dbus.MakeVariant(struct {
+		Width, Height, Rowstride int32
+		HasAlpha                 bool
+		BitsPerSample, Channels  int32
+		Data                     []byte
+	}{width, height, rowstride, channels == 4, 8, channels, data})
+}
+
+// historyIcon returns the image the daemon picked for notification id.
+func historyIcon(id uint32) string {
+	var data string
+	wigo.Call(wigoName+".GetHistory", 0, map[string]dbus.Variant{}).Store(&data)
+	var history []struct {
+		ID      uint32 `json:"id"`
+		AppIcon string `json:"app_icon"`
+	}
+	json.Unmarshal([]byte(data), &history)
+	for _, n := range history {
+		if n.ID == id {
+			return n.AppIcon
+		}
+	}
+	return ""
+}
+
+func decodeConfig(file string) (image.Config, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	return png.DecodeConfig(f)
+}
+
+// control checks the org.wigo.Notifications extension.
+func control() {
+	id := notify("conformance", 0, nil, 0)
+	var dismissed uint32
+	err := wigo.Call(wigoName+".DismissAll", 0).Store(&dismissed)
+	reason := waitClosed(id, time.Second)
+	check("DismissAll", err == nil && dismissed > 0 && reason == 2, "dismissed %d, reason %d, err %v", dismissed, reason, err)
+
+	var history string
+	err = wigo.Call(wigoName+".GetHistory", 0, map[string]dbus.Variant{"limit": dbus.MakeVariant(uint32(1))}).Store(&history)
+	check("GetHistory", err == nil && strings.Contains(history, fmt.Sprintf(`"id":%d`, id)), "got %s, err %v", history, err)
+
+	var on bool
+	err = wigo.Call(wigoName+".SetDND", 0, "on").Store(&on)
+	check("SetDND", err == nil && on, "on %v, err %v", on, err)
+	var (
+		reasonDND string
+		until     int64
+	)
+	err = wigo.Call(wigoName+".GetDND", 0).Store(&on, &reasonDND, &until)
+	check("GetDND", err == nil && on && reasonDND == "manual", "on %v, reason %q, err %v", on, reasonDND, err)
+	wigo.Call(wigoName+".SetDND", 0, "off")
+}
+
+func replies() {
+	id := notify("conformance", 0, nil, 0)
+	wigo.Call(wigoName+".InvokeActionWithToken", 0, id, "default", "token-1")
+	token := waitSignal("ActivationToken", id, time.Second)
+	action := waitSignal("ActionInvoked", id, time.Second)
+	check("ActivationToken comes with the action", token != nil && action != nil && token.Body[1] == "token-1",
+		"token %v, action %v", token, action)
+	waitClosed(id, time.Second)
+
+	var replyID uint32
+	obj.Call(busName+".Notify", 0, "conformance", uint32(0), "", "summary", "body",
+		[]string{"inline-reply", "Reply"}, map[string]dbus.Variant{}, int32(0)).Store(&replyID)
+	err := wigo.Call(wigoName+".Reply", 0, replyID, "hello").Err
+	replied := waitSignal("NotificationReplied", replyID, time.Second)
+	check("Reply emits NotificationReplied", err == nil && replied != nil && replied.Body[1] == "hello",
+		"signal %v, err %v", replied, err)
+
+	err = wigo.Call(wigoName+".Reply", 0, id, "hello").Err
+	check("Reply needs an inline-reply action", err != nil, "no error")
+}